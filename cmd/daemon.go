@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/daemon"
+	"github.com/KalebHawkins/gofailover/failover"
+	"github.com/KalebHawkins/gofailover/schedule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the failovers of the configured clusters at their scheduled times.",
+	Long: `Run the failovers of the configured clusters at their scheduled times.
+
+The jobs of the "daemon" section of the configuration file are run at their time of day or cron
+expression, the schedule of each profile then decides what the run does. The configuration file
+is read again on SIGHUP.
+
+The last scheduled run of each job is kept in the state file. A run missed while the daemon was
+not running is performed when the daemon starts if it is late by less than the grace period, it is
+reported as missed by email otherwise.
+
+Under systemd use Type=notify: readiness, reloads and the watchdog are reported with sd_notify.
+Use --next to print the next runs of the jobs and exit.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exit(runDaemon())
+	},
+}
+
+var showNext bool
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().BoolVar(&showNext, "next", false, "print the next runs of the jobs and exit")
+}
+
+// DefaultStateFile is the state file of the daemon when `daemon.stateFile` is not set.
+const DefaultStateFile = "/var/lib/gofailover/state.json"
+
+// daemonTimeFormat is the format of the run times printed by the daemon.
+const daemonTimeFormat = "Mon 2006-01-02 15:04 MST"
+
+// defaultJobTime is the time of day of a job without `at` or `cron`. PKM runs batch jobs at 2, 3 and 4am.
+const defaultJobTime = "04:30"
+
+// errDaemonFlags is returned when flags meant for a single run are used with the daemon.
+var errDaemonFlags = errors.New("--override, --dry-run and --as-of cannot be used with the daemon")
+
+// daemonConfig is the `daemon` section of the configuration file.
+//
+// Example config:
+//
+//	daemon:
+//	  stateFile: /var/lib/gofailover/state.json
+//	  grace: 1h
+//	  jobs:
+//	    - profile: pkm
+//	      at: "04:30"
+//	    - profile: dw
+//	      cron: "0 5 * * *"
+type daemonConfig struct {
+	StateFile string        `mapstructure:"stateFile"`
+	Grace     time.Duration `mapstructure:"grace"`
+	Jobs      []daemonJob   `mapstructure:"jobs"`
+}
+
+// daemonJob runs the profile every day at the time of day `At`, or at the times of the cron expression `Cron`.
+type daemonJob struct {
+	Profile string `mapstructure:"profile"`
+	At      string `mapstructure:"at"`
+	Cron    string `mapstructure:"cron"`
+}
+
+// runDaemon runs the scheduler until SIGINT or SIGTERM is received.
+func runDaemon() (failover.Outcome, error) {
+	if override || dryRun || asOf != "" {
+		return failover.OutcomeError, errDaemonFlags
+	}
+
+	if showNext {
+		return printNextRuns()
+	}
+
+	scheduler := &daemon.Scheduler{
+		Load:   loadDaemonConfig,
+		RunJob: runJob,
+		Missed: reportMissed,
+		Logf: func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	if err := scheduler.Run(ctx, reload); err != nil {
+		return failover.OutcomeError, err
+	}
+
+	return failover.OutcomeSuccess, nil
+}
+
+// daemonSettings is the configuration file of the jobs the scheduler runs. It is only replaced once the scheduler
+// accepts a reloaded configuration, so a broken one read on `SIGHUP` never replaces the one the jobs were scheduled with.
+var daemonSettings *viper.Viper
+
+// loadDaemonConfig reads the configuration file again and returns the jobs of its `daemon` section.
+// The profile of every job is checked so a broken configuration is rejected before the first run.
+// The configuration file is read into a new instance which replaces `daemonSettings` when the scheduler commits it.
+func loadDaemonConfig() (daemon.Config, error) {
+	var config daemon.Config
+
+	v := viper.New()
+	setupConfig(v)
+	if err := v.ReadInConfig(); err != nil {
+		return config, err
+	}
+
+	var dc daemonConfig
+	if err := v.UnmarshalKey("daemon", &dc, decodeHook); err != nil {
+		return config, fmt.Errorf("failed to read `daemon` from the configuration file: %v", err)
+	}
+
+	if len(dc.Jobs) == 0 {
+		return config, errors.New("no jobs in the `daemon` section of the configuration file")
+	}
+
+	loc, err := loadLocation(v)
+	if err != nil {
+		return config, err
+	}
+
+	profiles, err := loadProfiles(v)
+	if err != nil {
+		return config, err
+	}
+
+	seen := make(map[string]bool)
+	for _, j := range dc.Jobs {
+		p, ok := profiles[j.Profile]
+		if !ok {
+			return config, fmt.Errorf("daemon: profile %v does not exist. Available profiles: %v", j.Profile, strings.Join(profileNames(profiles), ", "))
+		}
+
+		if seen[j.Profile] {
+			return config, fmt.Errorf("daemon: profile %v has more than one job", j.Profile)
+		}
+		seen[j.Profile] = true
+
+		if _, err := newEngine(v, j.Profile, p); err != nil {
+			return config, fmt.Errorf("daemon: profile %v: %v", j.Profile, err)
+		}
+
+		job, err := j.compile()
+		if err != nil {
+			return config, fmt.Errorf("daemon: profile %v: %v", j.Profile, err)
+		}
+		config.Jobs = append(config.Jobs, job)
+	}
+
+	config.Location = loc
+	config.Grace = dc.Grace
+	config.StateFile = dc.StateFile
+	if config.StateFile == "" {
+		config.StateFile = DefaultStateFile
+	}
+
+	config.Commit = func() { daemonSettings = v }
+	return config, nil
+}
+
+// compile returns the job with its cron expression.
+func (j daemonJob) compile() (daemon.Job, error) {
+	job := daemon.Job{Name: j.Profile, Spec: j.Cron}
+
+	if j.At != "" && j.Cron != "" {
+		return job, errors.New("`at` and `cron` cannot be used together")
+	}
+
+	if j.Cron == "" {
+		at := j.At
+		if at == "" {
+			at = defaultJobTime
+		}
+
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			return job, fmt.Errorf("invalid time of day %q, expected 15:04", at)
+		}
+		job.Spec = fmt.Sprintf("%v %v * * *", t.Minute(), t.Hour())
+	}
+
+	var err error
+	job.Cron, err = schedule.ParseCron(job.Spec)
+	return job, err
+}
+
+// runJob runs the failover of the profile of the job, evaluating its schedule for the scheduled time.
+// The profile is read from `daemonSettings`.
+func runJob(ctx context.Context, job daemon.Job, scheduled time.Time) string {
+	profiles, err := loadProfiles(daemonSettings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return failover.OutcomeError.String()
+	}
+
+	engine, err := newEngine(daemonSettings, job.Name, profiles[job.Name])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return failover.OutcomeError.String()
+	}
+	engine.Now = func() time.Time { return scheduled }
+
+	outcome, err := engine.Run(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return outcome.String()
+}
+
+// reportMissed sends an email listing the missed runs of the job.
+func reportMissed(job daemon.Job, scheduled []time.Time) {
+	name := job.Name
+	var subject string
+	if profiles, err := loadProfiles(daemonSettings); err == nil {
+		if n := profiles[job.Name].Notification.Name; n != "" {
+			name = n
+		}
+		subject = profiles[job.Name].Notification.Subject
+	}
+	if subject == "" {
+		subject = fmt.Sprintf("%v failover run missed", name)
+	}
+
+	msg := fmt.Sprintf("The following scheduled failover runs of the %v nodes were missed and will not be performed.\n", name)
+	msg += "The failover daemon was not running, or the host was down, at the scheduled time. Please verify the cluster and run the failover manually if needed.\n\n"
+	for _, t := range scheduled {
+		msg += fmt.Sprintf("  %v (%v)\n", t.Format(daemonTimeFormat), job.Spec)
+	}
+
+	if err := sendEmail(daemonSettings, subject, msg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// printNextRuns prints the next and last runs of the jobs.
+func printNextRuns() (failover.Outcome, error) {
+	config, err := loadDaemonConfig()
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+
+	state, err := daemon.LoadState(config.StateFile)
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSCHEDULE\tNEXT RUN\tLAST RUN\tLAST OUTCOME")
+	for _, job := range config.Jobs {
+		js := state.Jobs[job.Name]
+
+		next := "-"
+		if t := job.Cron.Next(time.Now().In(config.Location)); !t.IsZero() {
+			next = t.Format(daemonTimeFormat)
+		}
+
+		last := "-"
+		if !js.LastRun.IsZero() {
+			last = js.LastRun.In(config.Location).Format(daemonTimeFormat)
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", job.Name, job.Spec, next, last, orDash(js.LastOutcome))
+	}
+
+	return failover.OutcomeSuccess, w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
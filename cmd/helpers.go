@@ -1,30 +1,58 @@
 package cmd
 
 import (
-	"bufio"
-	"encoding/xml"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/smtp"
 	"os"
-	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/failover"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/lock"
+	"github.com/KalebHawkins/gofailover/runner"
 	"github.com/spf13/viper"
 )
 
-// ? May implement an interface system in a later version...
-// ? right now this is an over complication of the code
-// type Cluster interface {
-// 	failoverCmd()
-// 	getPrimaryNode() (string, error)
-// 	healthCheck()
-// 	handleError(error, crm.ClusterStatus)
-// 	startFailover()
-// 	handleSuccess()
-// }
+// executor runs the external commands.
+var executor runner.Executor = &runner.OS{Output: os.Stdout}
+
+// loggingExecutor prints every command before running it.
+type loggingExecutor struct {
+	runner.Executor
+}
+
+func (l *loggingExecutor) Run(ctx context.Context, cmd runner.Command) (runner.Result, error) {
+	fmt.Println("Running:", cmd)
+	return l.Executor.Run(ctx, cmd)
+}
+
+// statusCommand caches the command returned by `detectStatusCommand`.
+var statusCommand runner.Command
+
+// detectStatusCommand returns the `crm_mon` command producing the xml status for the installed Pacemaker
+// version. The legacy `crm_mon -fA1 --as-xml` is used if the version cannot be detected.
+func detectStatusCommand(ctx context.Context) runner.Command {
+	if len(statusCommand.Args) > 0 {
+		return statusCommand
+	}
+
+	var version crm.Version
+	if res, err := executor.Run(ctx, runner.NewCommand("crm_mon", "--version")); err == nil {
+		if v, err := crm.ParseVersion(res.Stdout); err == nil {
+			version = v
+		}
+	}
+
+	statusCommand = runner.NewCommand(crm.StatusArgs(version)...)
+	return statusCommand
+}
 
 // Email Stuff
 var (
@@ -34,153 +62,221 @@ var (
 	smtpPort  string
 )
 
-// generateDayMap is used to populate a global `dayMap` variable.
-// This function is called only one in the `root.go` `rootCMD` `init()`
-// function.
-func generateDayMap() {
-	dayMap = make(map[string][]int)
+// errNoTargetPrimaryNode is returned when `targetPrimaryNode` is missing from the configuration file.
+var errNoTargetPrimaryNode = errors.New("`targetPrimaryNode` is not set in the configuration file")
 
-	var i int
-	for i = 1; i <= daysIn(time.Now().Month()); i++ {
-		newDate := time.Date(time.Now().Year(), time.Now().Month(), i, 0, 0, 0, 0, time.Local)
+// errAsOfWithoutDryRun is returned when `--as-of` is used to run a failover for real.
+var errAsOfWithoutDryRun = errors.New("--as-of can only be used with --dry-run")
 
-		if newDate.Month() != time.Now().Month() {
-			break
-		}
-
-		dayMap[newDate.Weekday().String()] = append(dayMap[newDate.Weekday().String()], i)
+// runFailover builds a `failover.Engine` for the profile named `name` from the configuration file and runs it.
+// The outcome of the run is returned along with the error that caused it to fail, if any.
+func runFailover(name string, p failover.Profile) (failover.Outcome, error) {
+	if asOf != "" && !dryRun {
+		return failover.OutcomeError, errAsOfWithoutDryRun
 	}
-}
 
-// getDay will return the ordinal day as an int and the weekday as a string
-// based on the `t time.Time` parameter passed to it. The oridnal day would be
-// which weekday of the month it is for example if it were to pass a time object where
-// it were the 2nd Sunday of the month the function would return the values `2` and `Sunday`.
-func getDay(t time.Time) (int, string) {
+	engine, err := newEngine(viper.GetViper(), name, p)
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+	engine.Override = override
 
-	var whichDay int
-	var weekDay string
+	// Trap SIGINT and SIGTERM so an interrupted failover is cleaned up and reported instead of
+	// leaving the cluster in an unknown state.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	for k, v := range dayMap {
-		for i, n := range v {
-			if k == t.Weekday().String() && n == t.Day() {
-				whichDay = i + 1
-				weekDay = k
-			}
-		}
+	if dryRun {
+		return planFailover(ctx, engine)
 	}
 
-	return whichDay, weekDay
-}
-
-// daysIn returns the last day of the month providing how many days are in month provided.
-func daysIn(m time.Month) int {
-	return time.Date(time.Now().Year(), m+1, 0, 0, 0, 0, 0, time.Local).Day()
+	return engine.Run(ctx)
 }
 
-//execCmd will execute a system command and return the output as a string value.
-// if a command is long running you can choose to stream the output of the command
-// from stdout by setting `streamStdOut` to true.
-func execCmd(cmd string, streamStdOut bool) string {
-	_, err := exec.LookPath(strings.Split(cmd, " ")[0])
+// newEngine builds a `failover.Engine` for the profile named `name` from the configuration file.
+func newEngine(v *viper.Viper, name string, p failover.Profile) (*failover.Engine, error) {
+	// Get what node should be considered the primary node of the cluster from the
+	// provided configuration file.
+	expectedPrimaryNode := v.GetString("targetPrimaryNode")
+	if expectedPrimaryNode == "" {
+		return nil, errNoTargetPrimaryNode
+	}
 
+	policy, err := loadHealthPolicy(v)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to execute command: `%s`\ncommand: %v was not found in $PATH\n", cmd, strings.Split(cmd, " ")[0])
-		os.Exit(1)
+		return nil, err
 	}
 
-	osCmd := exec.Command("bash", "-c", cmd)
+	sched, err := loadSchedule(v, p)
+	if err != nil {
+		return nil, err
+	}
 
-	stdout, err := osCmd.StdoutPipe()
+	now, err := loadClock(v)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	err = osCmd.Start()
+	lockConfig, err := loadLock(v)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	var str string
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		if streamStdOut {
-			fmt.Println(scanner.Text())
+	// Resources ignored by the profile are ignored on top of the ones ignored by the policy.
+	policy.Ignore = append(policy.Ignore, p.HealthCheck.IgnoreResources...)
+
+	status := func(ctx context.Context) (crm.ClusterStatus, error) {
+		if timeout := p.Timeouts.Status; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
 		}
-		str += scanner.Text()
-	}
-	err = osCmd.Wait()
 
-	if err != nil {
-		panic(err)
+		cmd := p.HealthCheck.Command
+		if len(cmd.Args) == 0 {
+			cmd = detectStatusCommand(ctx)
+		}
+
+		res, err := executor.Run(ctx, cmd)
+		if err != nil {
+			return crm.ClusterStatus{}, err
+		}
+		cs, err := crm.ParseStatus(strings.NewReader(res.Stdout))
+		if err != nil {
+			return crm.ClusterStatus{}, err
+		}
+		return *cs, nil
 	}
 
-	return str
-}
+	cluster := &failover.ProfileCluster{
+		Profile:  p,
+		Executor: &loggingExecutor{executor},
+		Status:   status,
+	}
+	if dryRun {
+		// Only the read-only checks run during a dry run, their commands are not logged to keep the output clean.
+		cluster.Executor = executor
+	}
 
-// getClusterStatus provided a reader containing the xml output from the command `crm_mon --as-xml`
-// will return a `crm.ClusterStatus` object. This object will contain cluster data such as nodes, node status,
-// resrouces, etc. (See `../crm/types.go`  for more information on the `crm.ClusterStatus` object.)
-func getClusterStatus(r io.Reader) crm.ClusterStatus {
-	scanner := bufio.NewScanner(r)
+	healthCheck := func(ctx context.Context, cs crm.ClusterStatus) health.Report {
+		report := health.Evaluate(cs)
+		if !p.Converge.SkipDCCheck {
+			report.Merge(health.CheckDC(ctx, executor, cs))
+		}
+		return report
+	}
 
-	var databytes []byte = make([]byte, 0)
-	for scanner.Scan() {
-		databytes = append(databytes, scanner.Bytes()...)
+	// Only one node of the cluster performs the run, see `lock.Locker`.
+	locker := &lock.Locker{
+		Config:   lockConfig,
+		Profile:  name,
+		Executor: executor,
+		Status:   status,
+		Logf: func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		},
 	}
 
-	var cs crm.ClusterStatus
-	if err := xml.Unmarshal(databytes, &cs); err != nil {
-		panic(err)
+	engine := &failover.Engine{
+		Cluster:             cluster,
+		ExpectedPrimaryNode: expectedPrimaryNode,
+		Schedule:            sched,
+		Now:                 now,
+		Rollback:            p.Rollback,
+		CleanupTimeout:      p.Timeouts.Cleanup,
+		Lock:                locker.Acquire,
+		Status:              status,
+		HealthCheck:         healthCheck,
+		Policy:              policy,
+		Notify: func(msg string) {
+			if err := sendEmail(v, p.Notification.Subject, msg); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		},
+		ErrorMessage:          p.Notification.Error,
+		SuccessMessage:        p.Notification.Success,
+		AbortedMessage:        p.Notification.Aborted,
+		RolledBackMessage:     p.Notification.RolledBack,
+		RollbackFailedMessage: p.Notification.RollbackFailed,
+		SkippedMessage:        p.Notification.Skipped,
 	}
 
-	return cs
+	return engine, nil
 }
 
-// isNodeHealthy returns true if a cluster node is in an online status only.
-// This function is called in the `isClusterHealthy` function.
-func isNodeHealthy(n crm.Node) bool {
-	return n.Online && !n.Standby && !n.Maintenance && !n.Pending && !n.Unclean && !n.Shutdown
-}
+// loadClock returns the clock the schedule is evaluated with. It returns the current time in the time zone
+// of the `timezone` setting, an IANA name such as `America/Chicago`, or in the local time zone if it is not set.
+// The date and time of the `--as-of` flag are returned instead if it is set. A date alone keeps the current time of day.
+//
+// Example config:
+//
+//	timezone: America/Chicago
+func loadClock(v *viper.Viper) (func() time.Time, error) {
+	loc, err := loadLocation(v)
+	if err != nil {
+		return nil, err
+	}
 
-// isClusterHealthy returns true if nodes check as healthy (see `isNodeHealthy`), and it checks to make sure all
-// resources are in a good state. This function does not check the attributes of nodes. That should be does on a
-// per cluster bases depending on what attributes are attached to your nodes.
-func isClusterHealthy(cs crm.ClusterStatus) error {
+	if asOf == "" {
+		return func() time.Time { return time.Now().In(loc) }, nil
+	}
 
-	for _, n := range cs.Nodes {
-		if !isNodeHealthy(n) {
-			return fmt.Errorf("%v is in an unhealthy state", n.Name)
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, asOf, loc); err == nil {
+			t = t.In(loc)
+			return func() time.Time { return t }, nil
 		}
 	}
 
-	for _, r := range cs.Resources.StandAlone {
-		if !r.Active && r.Blocked && r.Failed {
-			return fmt.Errorf("resource %v is not in a healthy state", r.Name)
-		}
+	date, err := time.ParseInLocation("2006-01-02", asOf, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --as-of %q, expected 2006-01-02 or 2006-01-02T15:04", asOf)
 	}
 
-	for _, g := range cs.Resources.Groups {
-		for _, r := range g.Resources {
-			if !r.Active && r.Blocked && r.Failed {
-				return fmt.Errorf("resource %v is not in a healthy state", r.Name)
-			}
-		}
+	return func() time.Time {
+		now := time.Now().In(loc)
+		return time.Date(date.Year(), date.Month(), date.Day(), now.Hour(), now.Minute(), now.Second(), 0, loc)
+	}, nil
+}
+
+// loadLocation returns the time zone of the `timezone` setting, the local time zone if it is not set.
+func loadLocation(v *viper.Viper) (*time.Location, error) {
+	tz := v.GetString("timezone")
+	if tz == "" {
+		return time.Local, nil
 	}
 
-	for _, c := range cs.Resources.Cloned {
-		for _, r := range c.Resources {
-			if !r.Active && r.Blocked && r.Failed {
-				return fmt.Errorf("resource %v is not in a healthy state", r.Name)
-			}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid `timezone` %q: %v", tz, err)
+	}
+
+	return loc, nil
+}
+
+// planFailover prints the plan of the engine in the format of the `--output` flag.
+func planFailover(ctx context.Context, engine *failover.Engine) (failover.Outcome, error) {
+	if output != "text" && output != "json" {
+		return failover.OutcomeError, fmt.Errorf("unknown output format %q, expected text or json", output)
+	}
+
+	plan, outcome, err := engine.Plan(ctx)
+
+	if output == "json" {
+		b, jsonErr := json.MarshalIndent(plan, "", "  ")
+		if jsonErr != nil {
+			return failover.OutcomeError, jsonErr
 		}
+		fmt.Println(string(b))
+		return outcome, err
 	}
 
-	return nil
+	fmt.Print(plan)
+	return outcome, err
 }
 
 // sendEmail sends an email message. The message is passed to the function as a string
-// and sent using the provided configuration.
+// and sent using the provided configuration. If `subject` is empty the `email.subject` setting is used.
 // Example config:
 // 	email:
 //     to:
@@ -188,23 +284,27 @@ func isClusterHealthy(cs crm.ClusterStatus) error {
 //     from: someone@example.com
 //     smtpHost: smtp.example.com
 //     smtpPort: 25
-func sendEmail(msg string) {
-	emailFrom = viper.GetString("email.from")
-	emailTo = viper.GetStringSlice("email.to")
-	smtpHost = viper.GetString("email.smtpHost")
-	smtpPort = viper.GetString("email.smtpPort")
-	subjectLine := viper.GetString("email.subject")
+func sendEmail(v *viper.Viper, subject, msg string) error {
+	emailFrom = v.GetString("email.from")
+	emailTo = v.GetStringSlice("email.to")
+	smtpHost = v.GetString("email.smtpHost")
+	smtpPort = v.GetString("email.smtpPort")
+	subjectLine := subject
+	if subjectLine == "" {
+		subjectLine = v.GetString("email.subject")
+	}
 
 	if emailFrom == "" || emailTo == nil || smtpHost == "" || smtpPort == "" {
-		fmt.Fprintf(os.Stderr, "email properties have not been set in the configuration file.\nNo emails will be sent out!\n")
+		return errors.New("email properties have not been set in the configuration file. No emails will be sent out")
 	}
 
 	// Message.
 	mailMessage := []byte("Subject: " + subjectLine + "\r\n\r\n" + msg)
 
 	// Sending email.
-	err := smtp.SendMail(smtpHost+":"+smtpPort, nil, emailFrom, emailTo, mailMessage)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v", err)
+	if err := smtp.SendMail(smtpHost+":"+smtpPort, nil, emailFrom, emailTo, mailMessage); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
+
+	return nil
 }
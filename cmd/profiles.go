@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/failover"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/lock"
+	"github.com/KalebHawkins/gofailover/pgrex"
+	"github.com/KalebHawkins/gofailover/runner"
+	"github.com/KalebHawkins/gofailover/schedule"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// switchover runs `pg-rex_switchover`, answering only its confirmation prompt.
+var switchover = pgrex.Switchover{Enabled: true}
+
+// pgsqlCheck checks the replication state of the `pgsql` resource before a `pg-rex_switchover`.
+// The standby may be at most one WAL segment behind the primary.
+var pgsqlCheck = failover.PostgresCheck{Resource: "pgsql", MaxLagBytes: 16 << 20}
+
+// defaultTimeouts are used by profiles without a `timeouts` section.
+var defaultTimeouts = failover.Timeouts{
+	Status:   time.Minute,
+	Failover: 15 * time.Minute,
+	Cleanup:  2 * time.Minute,
+}
+
+// builtinProfiles are the profiles available without any `profiles` section in the configuration file.
+// A profile with the same name in the configuration file replaces the built-in profile.
+var builtinProfiles = map[string]failover.Profile{
+	"pkm": {
+		Primary:      failover.PrimaryRule{Attribute: "pgsql-status", Value: "PRI"},
+		Switchover:   switchover,
+		Postgres:     pgsqlCheck,
+		Notification: failover.Notification{Name: "PKM database"},
+	},
+	"sums": {
+		Primary:      failover.PrimaryRule{Attribute: "pgsql-status", Value: "PRI"},
+		Switchover:   switchover,
+		Postgres:     pgsqlCheck,
+		Notification: failover.Notification{Name: "SUMS database"},
+	},
+	"dw": {
+		Primary: failover.PrimaryRule{Group: "dwgrp"},
+		// Moving the PCS resource indirectly creates a location constraint on the resource.
+		// So we need to make sure that once the resource is moved we clear that location constraint.
+		// The resources have to be running on the other node before the constraint is cleared,
+		// otherwise they would move straight back.
+		Failover: []runner.Command{runner.NewCommand("pcs", "resource", "move", "dwgrp")},
+		Cleanup:  []runner.Command{runner.NewCommand("pcs", "resource", "clear", "dwgrp")},
+		// Confirm that the location constraints were removed.
+		Verify: []failover.Verification{
+			{
+				Command: runner.NewCommand("pcs", "constraint", "location"),
+				Reject:  "Node:",
+				Message: "failed to clear location constraints. Please login to one of the cluster nodes and run `pcs resource clear dwgrp` manually to attempt to clear constraints",
+			},
+		},
+		Notification: failover.Notification{Name: "DeviceWISE"},
+	},
+}
+
+// loadProfiles returns the built-in profiles merged with the profiles from the `profiles` section of the configuration file.
+func loadProfiles(v *viper.Viper) (map[string]failover.Profile, error) {
+	profiles := make(map[string]failover.Profile)
+	for name, p := range builtinProfiles {
+		profiles[name] = p
+	}
+
+	var configured map[string]failover.Profile
+	if err := v.UnmarshalKey("profiles", &configured, decodeHook); err != nil {
+		return nil, fmt.Errorf("failed to read `profiles` from the configuration file: %v", err)
+	}
+
+	for name, p := range configured {
+		profiles[name] = p
+	}
+
+	for name, p := range profiles {
+		if p.Notification.Name == "" {
+			p.Notification.Name = name
+		}
+
+		if p.Timeouts.Status == 0 {
+			p.Timeouts.Status = defaultTimeouts.Status
+		}
+
+		if p.Timeouts.Failover == 0 {
+			p.Timeouts.Failover = defaultTimeouts.Failover
+		}
+
+		if p.Timeouts.Cleanup == 0 {
+			p.Timeouts.Cleanup = defaultTimeouts.Cleanup
+		}
+
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("profile %v: %v", name, err)
+		}
+
+		profiles[name] = p
+	}
+
+	return profiles, nil
+}
+
+// loadHealthPolicy returns the `healthPolicy` section of the configuration file.
+func loadHealthPolicy(v *viper.Viper) (health.Policy, error) {
+	var policy health.Policy
+	if err := v.UnmarshalKey("healthPolicy", &policy, decodeHook); err != nil {
+		return policy, fmt.Errorf("failed to read `healthPolicy` from the configuration file: %v", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf("healthPolicy: %v", err)
+	}
+
+	return policy, nil
+}
+
+// loadLock returns the `lock` section of the configuration file.
+func loadLock(v *viper.Viper) (lock.Config, error) {
+	var config lock.Config
+	if err := v.UnmarshalKey("lock", &config, decodeHook); err != nil {
+		return config, fmt.Errorf("failed to read `lock` from the configuration file: %v", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("lock: %v", err)
+	}
+
+	return config, nil
+}
+
+// loadSchedule compiles the schedule of the profile. The rules of the profile replace the rules of the `schedule`
+// section of the configuration file, the default rules for the `whatDay` setting are used if neither has any.
+// The blackouts of the profile, of the `schedule` section and of the `blackouts` section are all applied.
+func loadSchedule(v *viper.Viper, p failover.Profile) (*schedule.Calendar, error) {
+	var sched schedule.Schedule
+	if err := v.UnmarshalKey("schedule", &sched, decodeHook); err != nil {
+		return nil, fmt.Errorf("failed to read `schedule` from the configuration file: %v", err)
+	}
+
+	var blackouts schedule.Blackouts
+	if err := v.UnmarshalKey("blackouts", &blackouts, decodeHook); err != nil {
+		return nil, fmt.Errorf("failed to read `blackouts` from the configuration file: %v", err)
+	}
+
+	if len(p.Schedule.Rules) > 0 {
+		sched.Rules = p.Schedule.Rules
+	}
+	if len(sched.Rules) == 0 {
+		sched.Rules = schedule.Default(v.GetString("whatDay")).Rules
+	}
+
+	sched.Blackouts = mergeBlackouts(p.Schedule.Blackouts, sched.Blackouts, blackouts)
+
+	cal, err := sched.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("schedule: %v", err)
+	}
+
+	return cal, nil
+}
+
+// mergeBlackouts returns all the dates, calendars and windows of the blackouts. The first mode and maximum
+// shift that are set win.
+func mergeBlackouts(all ...schedule.Blackouts) schedule.Blackouts {
+	var merged schedule.Blackouts
+	for _, b := range all {
+		if merged.Mode == "" {
+			merged.Mode = b.Mode
+		}
+		if merged.MaxShift == 0 {
+			merged.MaxShift = b.MaxShift
+		}
+
+		merged.Dates = append(merged.Dates, b.Dates...)
+		merged.ICS = append(merged.ICS, b.ICS...)
+		merged.Windows = append(merged.Windows, b.Windows...)
+	}
+
+	return merged
+}
+
+// profileNames returns the sorted names of the profiles.
+func profileNames(profiles map[string]failover.Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// decodeHook decodes durations, commands and values implementing `encoding.TextUnmarshaler`, such as
+// severities, when reading the configuration file.
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.TextUnmarshallerHookFunc(),
+	commandDecodeHook,
+))
+
+// commandDecodeHook allows commands in the configuration file to be written as a command line
+// (`pcs resource move dwgrp`) or a list of arguments (`[pcs, resource, move, dwgrp]`) in addition
+// to the full `runner.Command` form.
+func commandDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(runner.Command{}) {
+		return data, nil
+	}
+
+	switch v := data.(type) {
+	case string:
+		return runner.ParseCommand(v)
+	case []interface{}:
+		cmd := runner.Command{}
+		for _, arg := range v {
+			cmd.Args = append(cmd.Args, fmt.Sprint(arg))
+		}
+		return cmd, nil
+	}
+
+	return data, nil
+}
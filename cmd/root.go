@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/KalebHawkins/gofailover/failover"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
-var dayMap map[string][]int
 var override bool
+var dryRun bool
+var output string
+var asOf string
 
 var rootCmd = &cobra.Command{
 	Use:   "failover",
@@ -28,29 +31,45 @@ func Execute() {
 	cobra.CheckErr(rootCmd.Execute())
 }
 
+// exit prints the error, if any, and exits with the exit code of the outcome.
+// See `failover.Outcome` for the list of exit codes.
+func exit(outcome failover.Outcome, err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	os.Exit(outcome.ExitCode())
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.test.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&override, "override", false, "run a subcommand regardless of the current date")
-
-	generateDayMap()
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print the commands a failover would run and why, without changing anything or sending email")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "text", "output format of the dry run and the schedule, text or json, or ics for the schedule")
+	rootCmd.PersistentFlags().StringVar(&asOf, "as-of", "", "evaluate the schedule for a date (2006-01-02) or time (2006-01-02T15:04) instead of now, requires --dry-run")
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	setupConfig(viper.GetViper())
+
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
+}
+
+// setupConfig sets where `v` reads the config file from and enables ENV variables.
+func setupConfig(v *viper.Viper) {
 	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
+		v.SetConfigFile(cfgFile)
 	} else {
 		home, err := os.UserHomeDir()
 		cobra.CheckErr(err)
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".test")
+		v.AddConfigPath(home)
+		v.SetConfigType("yaml")
+		v.SetConfigName(".test")
 	}
 
-	viper.AutomaticEnv()
-
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
-	}
+	v.AutomaticEnv()
 }
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KalebHawkins/gofailover/failover"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <profile>",
+	Short: "Perform the failover of a cluster described by a profile.",
+	Long: `Perform the failover of a cluster described by a profile.
+
+Profiles are read from the "profiles" section of the configuration file. The built-in
+profiles "pkm", "sums" and "dw" are always available unless replaced in the configuration file.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exit(runProfile(args[0]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+}
+
+// runProfile loads the profile by name and performs its failover.
+func runProfile(name string) (failover.Outcome, error) {
+	profiles, err := loadProfiles(viper.GetViper())
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+
+	p, ok := profiles[name]
+	if !ok {
+		return failover.OutcomeError, fmt.Errorf("profile %v does not exist. Available profiles: %v", name, strings.Join(profileNames(profiles), ", "))
+	}
+
+	return runFailover(name, p)
+}
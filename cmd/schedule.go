@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/failover"
+	"github.com/KalebHawkins/gofailover/schedule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Show what the scheduler would do on each date of a range.",
+	Long: `Show what the scheduler would do on each date of a range.
+
+Every date with a scheduled action is listed with the rule that produced it: failover,
+failback-if-needed, verify-only, or skipped or shifted because of a blackout. Whether a
+failover is actually performed also depends on the primary node at the time of the run.
+
+The schedule of the configuration file is used unless --profile is set. Use --output json or
+--output ics to publish the calendar.`,
+	Example: `  failover schedule --from 2026-11-01 --to 2027-03-31 --profile pkm
+  failover schedule --from 2026-11-01 --to 2027-03-31 --output ics > failover.ics`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exit(showSchedule())
+	},
+}
+
+var scheduleFrom string
+var scheduleTo string
+var scheduleProfile string
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+
+	scheduleCmd.Flags().StringVar(&scheduleFrom, "from", "", "first date of the range, 2006-01-02 (default today)")
+	scheduleCmd.Flags().StringVar(&scheduleTo, "to", "", "last date of the range, 2006-01-02 (default 3 months after --from)")
+	scheduleCmd.Flags().StringVarP(&scheduleProfile, "profile", "p", "", "profile whose schedule is shown")
+}
+
+// scheduleEntry is a date of the schedule in the json output.
+type scheduleEntry struct {
+	Date        string          `json:"date"`
+	Weekday     string          `json:"weekday"`
+	Action      schedule.Action `json:"action"`
+	Summary     string          `json:"summary"`
+	Rule        string          `json:"rule"`
+	Blackout    string          `json:"blackout,omitempty"`
+	ShiftedFrom string          `json:"shiftedFrom,omitempty"`
+	ShiftedTo   string          `json:"shiftedTo,omitempty"`
+}
+
+// showSchedule prints the decisions of the schedule for the range of the `--from` and `--to` flags.
+func showSchedule() (failover.Outcome, error) {
+	if output != "text" && output != "json" && output != "ics" {
+		return failover.OutcomeError, fmt.Errorf("unknown output format %q, expected text, json or ics", output)
+	}
+
+	loc, err := loadLocation(viper.GetViper())
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+
+	from := time.Now().In(loc)
+	if scheduleFrom != "" {
+		if from, err = time.ParseInLocation("2006-01-02", scheduleFrom, loc); err != nil {
+			return failover.OutcomeError, fmt.Errorf("invalid --from %q, expected 2006-01-02", scheduleFrom)
+		}
+	}
+
+	to := from.AddDate(0, 3, 0)
+	if scheduleTo != "" {
+		if to, err = time.ParseInLocation("2006-01-02", scheduleTo, loc); err != nil {
+			return failover.OutcomeError, fmt.Errorf("invalid --to %q, expected 2006-01-02", scheduleTo)
+		}
+	}
+
+	if to.Before(from) {
+		return failover.OutcomeError, fmt.Errorf("--to %v is before --from %v", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+
+	name := "failover"
+	var p failover.Profile
+	if scheduleProfile != "" {
+		profiles, err := loadProfiles(viper.GetViper())
+		if err != nil {
+			return failover.OutcomeError, err
+		}
+
+		var ok bool
+		if p, ok = profiles[scheduleProfile]; !ok {
+			return failover.OutcomeError, fmt.Errorf("profile %v does not exist. Available profiles: %v", scheduleProfile, strings.Join(profileNames(profiles), ", "))
+		}
+		name = p.Notification.Name
+	}
+
+	cal, err := loadSchedule(viper.GetViper(), p)
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+
+	decisions := cal.Between(from, to)
+
+	switch output {
+	case "ics":
+		err = schedule.WriteICS(os.Stdout, name, decisions)
+	case "json":
+		entries := make([]scheduleEntry, 0, len(decisions))
+		for _, d := range decisions {
+			entries = append(entries, newScheduleEntry(d))
+		}
+
+		var b []byte
+		if b, err = json.MarshalIndent(entries, "", "  "); err == nil {
+			fmt.Println(string(b))
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DATE\tDAY\tACTION\tRULE\tNOTE")
+		for _, d := range decisions {
+			e := newScheduleEntry(d)
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", e.Date, e.Weekday[:3], e.Summary, e.Rule, scheduleNote(e))
+		}
+		err = w.Flush()
+	}
+
+	if err != nil {
+		return failover.OutcomeError, err
+	}
+
+	return failover.OutcomeSuccess, nil
+}
+
+func newScheduleEntry(d schedule.Decision) scheduleEntry {
+	e := scheduleEntry{
+		Date:     d.Date.Format("2006-01-02"),
+		Weekday:  d.Date.Weekday().String(),
+		Action:   d.Action,
+		Rule:     d.Rule.String(),
+		Blackout: d.Blackout,
+	}
+
+	switch d.Action {
+	case schedule.ActionFailover:
+		e.Summary = "failover"
+	case schedule.ActionFailback:
+		e.Summary = "failback-if-needed"
+	default:
+		e.Summary = string(d.Action)
+	}
+
+	if !d.ShiftedFrom.IsZero() {
+		e.ShiftedFrom = d.ShiftedFrom.Format("2006-01-02")
+	}
+
+	if d.Blackout != "" {
+		e.Summary = "skipped (blackout)"
+		if !d.ShiftedTo.IsZero() {
+			e.ShiftedTo = d.ShiftedTo.Format("2006-01-02")
+			e.Summary = "shifted (blackout)"
+		}
+	}
+
+	return e
+}
+
+// scheduleNote explains a skipped or shifted action in the table output.
+func scheduleNote(e scheduleEntry) string {
+	switch {
+	case e.ShiftedTo != "":
+		return fmt.Sprintf("%v to %v: %v", e.Action, e.ShiftedTo, e.Blackout)
+	case e.Blackout != "":
+		return fmt.Sprintf("%v: %v", e.Action, e.Blackout)
+	case e.ShiftedFrom != "":
+		return "shifted from " + e.ShiftedFrom
+	}
+
+	return ""
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/schedule"
+)
+
+func TestNewScheduleEntry(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, time.November, d, 0, 0, 0, 0, time.UTC) }
+	rule := schedule.Rule{Name: "1st Sunday", Action: schedule.ActionFailover}
+	freeze := "change freeze (2026-11-01 to 2026-11-03)"
+
+	tests := []struct {
+		name     string
+		decision schedule.Decision
+		want     scheduleEntry
+		note     string
+	}{
+		{
+			name:     "no rule",
+			decision: schedule.Decision{Date: day(2)},
+			want:     scheduleEntry{Date: "2026-11-02", Weekday: "Monday", Rule: schedule.Rule{}.String()},
+		},
+		{
+			name:     "failover",
+			decision: schedule.Decision{Date: day(1), Rule: rule, Action: schedule.ActionFailover},
+			want:     scheduleEntry{Date: "2026-11-01", Weekday: "Sunday", Action: schedule.ActionFailover, Summary: "failover", Rule: "1st Sunday"},
+		},
+		{
+			name:     "failback",
+			decision: schedule.Decision{Date: day(8), Rule: rule, Action: schedule.ActionFailback},
+			want:     scheduleEntry{Date: "2026-11-08", Weekday: "Sunday", Action: schedule.ActionFailback, Summary: "failback-if-needed", Rule: "1st Sunday"},
+		},
+		{
+			name:     "verify only",
+			decision: schedule.Decision{Date: day(15), Rule: rule, Action: schedule.ActionVerify},
+			want:     scheduleEntry{Date: "2026-11-15", Weekday: "Sunday", Action: schedule.ActionVerify, Summary: "verify-only", Rule: "1st Sunday"},
+		},
+		{
+			name:     "skipped blackout",
+			decision: schedule.Decision{Date: day(1), Rule: rule, Action: schedule.ActionFailover, Blackout: freeze},
+			want: scheduleEntry{Date: "2026-11-01", Weekday: "Sunday", Action: schedule.ActionFailover, Summary: "skipped (blackout)",
+				Rule: "1st Sunday", Blackout: freeze},
+			note: "failover-to-secondary: " + freeze,
+		},
+		{
+			name:     "shifted blackout",
+			decision: schedule.Decision{Date: day(1), Rule: rule, Action: schedule.ActionFailover, Blackout: freeze, ShiftedTo: day(4)},
+			want: scheduleEntry{Date: "2026-11-01", Weekday: "Sunday", Action: schedule.ActionFailover, Summary: "shifted (blackout)",
+				Rule: "1st Sunday", Blackout: freeze, ShiftedTo: "2026-11-04"},
+			note: "failover-to-secondary to 2026-11-04: " + freeze,
+		},
+		{
+			name:     "shifted action",
+			decision: schedule.Decision{Date: day(4), Rule: rule, Action: schedule.ActionFailover, ShiftedFrom: day(1)},
+			want: scheduleEntry{Date: "2026-11-04", Weekday: "Wednesday", Action: schedule.ActionFailover, Summary: "failover",
+				Rule: "1st Sunday", ShiftedFrom: "2026-11-01"},
+			note: "shifted from 2026-11-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newScheduleEntry(tt.decision)
+			if got != tt.want {
+				t.Errorf("newScheduleEntry() = %+v, want %+v", got, tt.want)
+			}
+			if note := scheduleNote(got); note != tt.note {
+				t.Errorf("scheduleNote() = %q, want %q", note, tt.note)
+			}
+		})
+	}
+}
@@ -1,38 +1,29 @@
 package cmd
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"strings"
 
 	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/failover"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/runner"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Return the status of a cluster and its nodes",
-	Long:  `Return the status of a cluster and its nodes`,
-	Run: func(cmd *cobra.Command, args []string) {
-
-		var cs crm.ClusterStatus
-		// If the file flag is specified we parse our data from a test xml file.
-		if file != "" {
-			cs = statusFromFile(file)
-			fmt.Println(cs)
-		} else { // If the file flag is not enabled then we pull the cluster status from the crm_mon -fA1 --as-xml command.
-			xml := execCmd("crm_mon -fA1 --as-xml", false)
-			cs = getClusterStatus(strings.NewReader(xml))
-			fmt.Println(cs)
-		}
+	Long: `Return the status of a cluster and its nodes.
 
-		// if the checkHealth flag is enabled then the cluster's nodes and resource states are checked.
-		if checkHealth {
-			isClusterHealthy(cs)
-		}
+When --health-check is used every problem found is listed with its severity and the command
+exits with the "pre-check failed" exit code (3) if any of them is critical. The ` + "`healthPolicy`" + `
+section of the configuration file is applied to the report.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exit(showStatus())
 	},
 }
 
@@ -46,18 +37,48 @@ func init() {
 	statusCmd.Flags().BoolVarP(&checkHealth, "health-check", "", false, "performs health check on the cluster")
 }
 
-// statusFromFile is a wrapper to pull the cluster status from a test xml file.
-func statusFromFile(filePath string) crm.ClusterStatus {
-	if _, err := os.Stat(file); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+// showStatus prints the cluster status and checks its health if the `health-check` flag is set.
+func showStatus() (failover.Outcome, error) {
+	var cs *crm.ClusterStatus
+	var err error
+
+	// If the file flag is specified we parse our data from a test xml file.
+	if file != "" {
+		cs, err = crm.ParseStatusFile(file)
+	} else { // If the file flag is not enabled then we pull the cluster status from crm_mon.
+		var res runner.Result
+		ctx := context.Background()
+		res, err = executor.Run(ctx, detectStatusCommand(ctx))
+		if err == nil {
+			cs, err = crm.ParseStatus(strings.NewReader(res.Stdout))
+		}
 	}
 
-	f, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		panic(err)
+		return failover.OutcomeError, err
+	}
+
+	fmt.Println(*cs)
+
+	// if the checkHealth flag is enabled then the cluster's nodes and resource states are checked.
+	if checkHealth {
+		policy, err := loadHealthPolicy(viper.GetViper())
+		if err != nil {
+			return failover.OutcomeError, err
+		}
+
+		report := health.Evaluate(*cs)
+		// The state of the DC can only be checked on a live cluster.
+		if file == "" {
+			report.Merge(health.CheckDC(context.Background(), executor, *cs))
+		}
+		report = policy.Apply(*cs, report)
+		fmt.Printf("Health Report:\n%v", report)
+
+		if err := report.Err(); err != nil {
+			return failover.OutcomePreCheckFailed, err
+		}
 	}
-	cs := getClusterStatus(bytes.NewReader(f))
 
-	return cs
+	return failover.OutcomeSuccess, nil
 }
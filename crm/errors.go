@@ -0,0 +1,15 @@
+package crm
+
+import "errors"
+
+// ErrMalformedStatus is returned when the `crm_mon` xml output cannot be parsed.
+var ErrMalformedStatus = errors.New("malformed cluster status")
+
+// ErrResourceNotFound is returned when a resource cannot be found in the cluster status.
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ErrResourceNotRunning is returned when a resource is not running on any node.
+var ErrResourceNotRunning = errors.New("resource is not running")
+
+// ErrNotPromotable is returned when a clone is expected to be promotable but is not.
+var ErrNotPromotable = errors.New("clone is not promotable")
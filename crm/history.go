@@ -0,0 +1,138 @@
+package crm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Infinity is the value pacemaker uses for `INFINITY` scores, fail counts and thresholds.
+const Infinity = 1000000
+
+// Score is an integer attribute that can be set to `INFINITY`, like fail counts and migration thresholds.
+type Score int
+
+// UnmarshalXMLAttr parses the score, including `INFINITY`, `+INFINITY` and `-INFINITY`.
+func (s *Score) UnmarshalXMLAttr(attr xml.Attr) error {
+	score, err := ParseScore(attr.Value)
+	if err != nil {
+		return fmt.Errorf("invalid score %q for %v", attr.Value, attr.Name.Local)
+	}
+
+	*s = score
+	return nil
+}
+
+// ParseScore parses a score such as a node attribute, including `INFINITY`, `+INFINITY` and `-INFINITY`.
+// An empty string is a score of 0.
+func ParseScore(value string) (Score, error) {
+	switch strings.ToUpper(value) {
+	case "INFINITY", "+INFINITY":
+		return Infinity, nil
+	case "-INFINITY":
+		return -Infinity, nil
+	case "":
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid score %q", value)
+	}
+
+	return Score(n), nil
+}
+
+// NodeHistory contains the operation history and fail counts of the resources of a node.
+type NodeHistory struct {
+	Node      string            `xml:"name,attr"`
+	Resources []ResourceHistory `xml:"resource_history"`
+}
+
+// ResourceHistory contains the fail count, migration threshold and operations of a resource on a node.
+type ResourceHistory struct {
+	ID                 string      `xml:"id,attr"`
+	Orphan             bool        `xml:"orphan,attr"`
+	MigrationThreshold Score       `xml:"migration-threshold,attr"`
+	FailCount          Score       `xml:"fail-count,attr"`
+	LastFailure        string      `xml:"last-failure,attr"`
+	Operations         []Operation `xml:"operation_history"`
+}
+
+// FailuresLeft returns how many more failures the resource can have on the node before it is moved away.
+// -1 is returned if the resource has no migration threshold.
+func (h ResourceHistory) FailuresLeft() int {
+	if h.MigrationThreshold <= 0 || h.MigrationThreshold >= Infinity {
+		return -1
+	}
+
+	left := int(h.MigrationThreshold - h.FailCount)
+	if left < 0 {
+		left = 0
+	}
+
+	return left
+}
+
+// Operation is an operation run on a resource, e.g. a `start` or `monitor`.
+type Operation struct {
+	Call         int    `xml:"call,attr"`
+	Task         string `xml:"task,attr"`
+	Interval     string `xml:"interval,attr"`
+	LastRCChange string `xml:"last-rc-change,attr"`
+	ExecTime     string `xml:"exec-time,attr"`
+	RC           int    `xml:"rc,attr"`
+	RCText       string `xml:"rc_text,attr"`
+}
+
+// Failure is a failed action of a resource.
+type Failure struct {
+	OpKey        string `xml:"op_key,attr"`
+	Node         string `xml:"node,attr"`
+	Task         string `xml:"task,attr"`
+	Interval     string `xml:"interval,attr"`
+	ExitStatus   string `xml:"exitstatus,attr"`
+	ExitReason   string `xml:"exitreason,attr"`
+	ExitCode     int    `xml:"exitcode,attr"`
+	Call         int    `xml:"call,attr"`
+	Status       string `xml:"status,attr"`
+	LastRCChange string `xml:"last-rc-change,attr"`
+}
+
+func (f Failure) String() string {
+	str := fmt.Sprintf("    %v on %v: %v (rc=%v, status=%v, last-rc-change=%v)", f.OpKey, f.Node, f.ExitStatus, f.ExitCode, f.Status, f.LastRCChange)
+	if f.ExitReason != "" {
+		str += fmt.Sprintf(" reason: %v", f.ExitReason)
+	}
+
+	return str + "\n"
+}
+
+// ResourcesOnNodeHistory returns the history of every resource on the node.
+func (cs ClusterStatus) ResourcesOnNodeHistory(node string) []ResourceHistory {
+	for _, n := range cs.History {
+		if n.Node == node {
+			return n.Resources
+		}
+	}
+
+	return nil
+}
+
+// ResourceHistory returns the history of the resource on the node or nil if there is none.
+func (cs ClusterStatus) ResourceHistory(node, id string) *ResourceHistory {
+	for _, n := range cs.History {
+		if n.Node != node {
+			continue
+		}
+
+		for i := range n.Resources {
+			if n.Resources[i].ID == id {
+				return &n.Resources[i]
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package crm
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestScoreUnmarshal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Score
+		wantErr bool
+	}{
+		{"3", 3, false},
+		{"0", 0, false},
+		{"INFINITY", Infinity, false},
+		{"+INFINITY", Infinity, false},
+		{"-INFINITY", -Infinity, false},
+		{"infinity", Infinity, false},
+		{"-5", -5, false},
+		{"lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		var h ResourceHistory
+		err := xml.Unmarshal([]byte(`<resource_history id="app" fail-count="`+tt.value+`"/>`), &h)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fail-count=%q error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil && h.FailCount != tt.want {
+			t.Errorf("fail-count=%q = %v, want %v", tt.value, h.FailCount, tt.want)
+		}
+	}
+}
+
+func TestFailuresLeft(t *testing.T) {
+	tests := []struct {
+		threshold, failCount Score
+		want                 int
+	}{
+		{3, 0, 3},
+		{3, 1, 2},
+		{3, 3, 0},
+		{3, 5, 0},
+		{Infinity, 1, -1},
+		{0, 1, -1},
+	}
+
+	for _, tt := range tests {
+		h := ResourceHistory{MigrationThreshold: tt.threshold, FailCount: tt.failCount}
+		if got := h.FailuresLeft(); got != tt.want {
+			t.Errorf("FailuresLeft() with migration-threshold=%v fail-count=%v = %v, want %v", tt.threshold, tt.failCount, got, tt.want)
+		}
+	}
+}
+
+func TestResourceHistory(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	tests := []struct {
+		node, id             string
+		threshold, failCount Score
+		lastFailure          string
+		operations           int
+	}{
+		{"node1", "vip", 3, 0, "", 1},
+		{"node1", "app", 3, 1, "Sat Oct 17 03:12:44 2026", 1},
+		{"node1", "pgsql", Infinity, 0, "", 1},
+		{"node2", "pgsql", Infinity, Infinity, "Sat Oct 17 02:58:12 2026", 1},
+	}
+
+	for _, tt := range tests {
+		h := cs.ResourceHistory(tt.node, tt.id)
+		if h == nil {
+			t.Errorf("ResourceHistory(%v, %v) = nil", tt.node, tt.id)
+			continue
+		}
+		if h.MigrationThreshold != tt.threshold || h.FailCount != tt.failCount || h.LastFailure != tt.lastFailure || len(h.Operations) != tt.operations {
+			t.Errorf("ResourceHistory(%v, %v) = %+v", tt.node, tt.id, h)
+		}
+	}
+
+	if h := cs.ResourceHistory("node2", "vip"); h != nil {
+		t.Errorf("ResourceHistory(node2, vip) = %+v, want nil", h)
+	}
+
+	if n := len(cs.ResourcesOnNodeHistory("node1")); n != 3 {
+		t.Errorf("ResourcesOnNodeHistory(node1) returned %v resources, want 3", n)
+	}
+
+	op := cs.ResourceHistory("node1", "app").Operations[0]
+	if op.Task != "monitor" || op.RC != 7 || op.RCText != "not running" || op.Call != 12 {
+		t.Errorf("operation = %+v, want the failed monitor", op)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	if len(cs.Failures) != 2 {
+		t.Fatalf("Failures = %v, want 2 failures", cs.Failures)
+	}
+
+	want := Failure{
+		OpKey:        "pgsql_start_0",
+		Node:         "node2",
+		Task:         "start",
+		Interval:     "0",
+		ExitStatus:   "error",
+		ExitReason:   "My data may be inconsistent",
+		ExitCode:     1,
+		Call:         18,
+		Status:       "complete",
+		LastRCChange: "Sat Oct 17 02:58:12 2026",
+	}
+	if cs.Failures[1] != want {
+		t.Errorf("Failures[1] = %+v, want %+v", cs.Failures[1], want)
+	}
+}
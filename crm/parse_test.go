@@ -0,0 +1,103 @@
+package crm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStatusSchemas(t *testing.T) {
+	legacy := parseFixture(t, "crm_mon.xml")
+	result := parseFixture(t, "pacemaker-result.xml")
+
+	if legacy.Schema != SchemaLegacy || legacy.Version != "2.0.2" {
+		t.Errorf("crm_mon.xml schema = %v version %v, want %v version 2.0.2", legacy.Schema, legacy.Version, SchemaLegacy)
+	}
+	if result.Schema != SchemaPacemakerResult || result.APIVersion != "2.20" {
+		t.Errorf("pacemaker-result.xml schema = %v api version %v, want %v api version 2.20", result.Schema, result.APIVersion, SchemaPacemakerResult)
+	}
+
+	// Both documents describe the same cluster, only the fields describing the document differ.
+	for _, cs := range []*ClusterStatus{legacy, result} {
+		cs.Schema, cs.Version, cs.APIVersion = "", "", ""
+		cs.Result.Code, cs.Result.Message = 0, ""
+	}
+	if !reflect.DeepEqual(legacy, result) {
+		t.Errorf("ParseStatus() of the two schemas differ:\n%v\n%v", legacy, result)
+	}
+
+	dc := result.Status.DesignatedController
+	if dc.Node != "node1" || !dc.Quorum {
+		t.Errorf("current_dc = %+v, want node1 with quorum", dc)
+	}
+
+	// The legacy `Master` and `Slave` roles are mapped to the 2.x names.
+	var roles []Role
+	for _, r := range result.FindResource("pgsql-clone").Primitives() {
+		roles = append(roles, r.Role)
+	}
+	if want := []Role{RolePromoted, RoleUnpromoted}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("pgsql roles = %v, want %v", roles, want)
+	}
+
+	if b := result.FindResource("httpd-bundle"); b == nil || b.Kind != KindBundle || len(b.Children) != 3 {
+		t.Errorf("FindResource(httpd-bundle) = %+v, want a bundle of 3 resources", b)
+	}
+}
+
+func TestParseStatusErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"empty", ""},
+		{"not xml", "crm_mon: command not found"},
+		{"unexpected root", `<cib/>`},
+		{"truncated", `<crm_mon version="2.0.2"><summary>`},
+		{"crm_mon failed", `<pacemaker-result api-version="2.20"><status code="102" message="Not connected"/></pacemaker-result>`},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseStatus(strings.NewReader(tt.doc)); !errors.Is(err, ErrMalformedStatus) {
+			t.Errorf("%v: ParseStatus() error = %v, want %v", tt.name, err, ErrMalformedStatus)
+		}
+	}
+}
+
+func TestStatusArgs(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"Pacemaker 1.1.23-1.el7\nWritten by Andrew Beekhof", "crm_mon -fA1 --as-xml"},
+		{"Pacemaker 2.0.1-4.el8\nWritten by Andrew Beekhof", "crm_mon -fA1 --as-xml"},
+		{"Pacemaker 2.0.2-3.el8\nWritten by Andrew Beekhof", "crm_mon -fA1 --as-xml"},
+		{"Pacemaker 2.0.3-5.el8\nWritten by Andrew Beekhof", "crm_mon -1 -fA --output-as=xml"},
+		{"Pacemaker 2.1.5-8.el9\nWritten by Andrew Beekhof", "crm_mon -1 -fA --output-as=xml"},
+		{"Pacemaker 3.0\nWritten by Andrew Beekhof", "crm_mon -1 -fA --output-as=xml"},
+	}
+
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) error = %v", tt.version, err)
+			continue
+		}
+
+		if got := strings.Join(StatusArgs(v), " "); got != tt.want {
+			t.Errorf("StatusArgs(%v) = %v, want %v", v, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("Pacemaker 2.0.2-3.el8_1.2")
+	if err != nil || v != (Version{Major: 2, Minor: 0, Patch: 2}) {
+		t.Errorf("ParseVersion() = %v, %v, want 2.0.2", v, err)
+	}
+
+	if _, err := ParseVersion("crm_mon: command not found"); err == nil {
+		t.Error("ParseVersion() error = nil, want an error")
+	}
+}
@@ -0,0 +1,95 @@
+package crm
+
+import "fmt"
+
+// FindResource returns the first resource of any kind with the id or nil if there is none.
+func (cs ClusterStatus) FindResource(id string) *Resource {
+	var found *Resource
+	cs.Resources.Walk(func(r *Resource) {
+		if found == nil && r.ID == id {
+			found = r
+		}
+	})
+
+	return found
+}
+
+// ResourcesOnNode returns the primitives running on the node.
+func (cs ClusterStatus) ResourcesOnNode(name string) []*Resource {
+	var resources []*Resource
+	for _, r := range cs.Resources.Primitives() {
+		if r.RunsOn(name) {
+			resources = append(resources, r)
+		}
+	}
+
+	return resources
+}
+
+// GroupLocation returns the node running the resource group. Members of a group are colocated with
+// the first member, so the node running the first member is returned.
+func (cs ClusterStatus) GroupLocation(name string) (string, error) {
+	g := cs.FindResource(name)
+	if g == nil || g.Kind != KindGroup {
+		return "", fmt.Errorf("group %v: %w", name, ErrResourceNotFound)
+	}
+
+	primitives := g.Primitives()
+	if len(primitives) == 0 || !primitives[0].Active || primitives[0].Node() == "" {
+		return "", fmt.Errorf("group %v: %w", name, ErrResourceNotRunning)
+	}
+
+	return primitives[0].Node(), nil
+}
+
+// NodeAttribute returns the value of the attribute of the node. False is returned if the node
+// does not have the attribute.
+func (cs ClusterStatus) NodeAttribute(node, key string) (string, bool) {
+	for _, a := range cs.Attributes {
+		if a.Node != node {
+			continue
+		}
+
+		for _, atr := range a.Attributes {
+			if atr.Name == key {
+				return atr.Value, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// NodeWithAttribute returns the first node with the attribute set to the value. False is returned
+// if there is no such node.
+func (cs ClusterStatus) NodeWithAttribute(key, value string) (string, bool) {
+	for _, a := range cs.Attributes {
+		for _, atr := range a.Attributes {
+			if atr.Name == key && atr.Value == value {
+				return a.Node, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// PromotedNode returns the node running the promoted (master) instance of the promotable clone.
+func (cs ClusterStatus) PromotedNode(cloneID string) (string, error) {
+	c := cs.FindResource(cloneID)
+	if c == nil || c.Kind != KindClone {
+		return "", fmt.Errorf("clone %v: %w", cloneID, ErrResourceNotFound)
+	}
+
+	if !c.Promotable {
+		return "", fmt.Errorf("clone %v: %w", cloneID, ErrNotPromotable)
+	}
+
+	for _, r := range c.Primitives() {
+		if r.Promoted() {
+			return r.Node(), nil
+		}
+	}
+
+	return "", fmt.Errorf("clone %v has no promoted instance: %w", cloneID, ErrResourceNotRunning)
+}
@@ -0,0 +1,215 @@
+package crm
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// ResourceKind is the kind of a resource: a primitive or one of the parents of primitives.
+type ResourceKind string
+
+// Kinds of resources.
+const (
+	KindPrimitive ResourceKind = "primitive"
+	KindGroup     ResourceKind = "group"
+	KindClone     ResourceKind = "clone"
+	KindBundle    ResourceKind = "bundle"
+)
+
+// Resource is a resource of the cluster. Primitives are the resources actually running on nodes.
+// Groups, clones and bundles are parents containing other resources in `Children`. Every resource
+// except the top level ones has its `Parent` set, so the resource tree can be walked in both directions.
+type Resource struct {
+	Kind ResourceKind
+	ID   string
+	// Agent is the resource agent of a primitive, e.g. `ocf::heartbeat:IPaddr2`.
+	Agent string
+	Role  Role
+	// TargetRole is the role the resource is configured to have, e.g. `Stopped` for a disabled resource.
+	// It is empty unless it was set explicitly.
+	TargetRole     Role
+	Active         bool
+	Orphaned       bool
+	Blocked        bool
+	Managed        bool
+	Failed         bool
+	FailureIgnored bool
+	// Pending is the operation in progress on the resource, if any, e.g. `Starting`.
+	Pending string
+	// Nodes are the nodes the resource is running on.
+	Nodes []string
+	// Promotable is set on clones whose instances can be promoted (master/slave clones).
+	Promotable bool
+
+	Parent   *Resource
+	Children []*Resource
+}
+
+func (r Resource) String() string {
+	fmtString := "      [ Name: %v | Agent: %v | Role: %v | Active: %v | Blocked: %v | Managed: %v | Failed: %v ]\n"
+
+	str := fmt.Sprintf(fmtString, r.ID, r.Agent, r.Role, r.Active, r.Blocked, r.Managed, r.Failed)
+
+	return str
+}
+
+// Node returns the first node the resource is running on or an empty string if it is not running.
+func (r *Resource) Node() string {
+	if len(r.Nodes) == 0 {
+		return ""
+	}
+
+	return r.Nodes[0]
+}
+
+// RunsOn returns true if the resource is running on the node.
+func (r *Resource) RunsOn(node string) bool {
+	for _, n := range r.Nodes {
+		if n == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Promoted returns true if the resource is a running promoted instance of a promotable clone.
+func (r *Resource) Promoted() bool {
+	return r.Kind == KindPrimitive && r.Role == RolePromoted && r.Active && r.Node() != ""
+}
+
+// Walk calls `fn` for the resource and all of its descendants, parents before their children.
+func (r *Resource) Walk(fn func(r *Resource)) {
+	fn(r)
+	for _, child := range r.Children {
+		child.Walk(fn)
+	}
+}
+
+// Primitives returns the primitives of the resource: the resource itself if it is a primitive or its
+// descendant primitives otherwise.
+func (r *Resource) Primitives() []*Resource {
+	var primitives []*Resource
+	r.Walk(func(r *Resource) {
+		if r.Kind == KindPrimitive {
+			primitives = append(primitives, r)
+		}
+	})
+
+	return primitives
+}
+
+// Resources is the resource tree of the cluster. It contains the top level resources in the order
+// they appear in the `crm_mon` output.
+type Resources []*Resource
+
+// Walk calls `fn` for every resource of the tree, parents before their children.
+func (rs Resources) Walk(fn func(r *Resource)) {
+	for _, r := range rs {
+		r.Walk(fn)
+	}
+}
+
+// Primitives returns every primitive of the tree.
+func (rs Resources) Primitives() []*Resource {
+	var primitives []*Resource
+	for _, r := range rs {
+		primitives = append(primitives, r.Primitives()...)
+	}
+
+	return primitives
+}
+
+// UnmarshalXML decodes the `resources` element of the `crm_mon` output into a resource tree.
+func (rs *Resources) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	children, err := decodeChildren(d, nil)
+	if err != nil {
+		return err
+	}
+
+	*rs = children
+	return nil
+}
+
+// kinds maps the elements of the `crm_mon` output to resource kinds.
+var kinds = map[string]ResourceKind{
+	"resource": KindPrimitive,
+	"group":    KindGroup,
+	"clone":    KindClone,
+	"bundle":   KindBundle,
+}
+
+// decodeChildren decodes the elements up to the end of the current element. Resource elements are
+// returned as children of `parent` and `node` elements are added to the nodes of `parent`.
+func decodeChildren(d *xml.Decoder, parent *Resource) ([]*Resource, error) {
+	var resources []*Resource
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return resources, nil
+		case xml.StartElement:
+			if kind, ok := kinds[t.Name.Local]; ok {
+				r := newResource(t, kind, parent)
+				if r.Children, err = decodeChildren(d, r); err != nil {
+					return nil, err
+				}
+				resources = append(resources, r)
+				continue
+			}
+
+			switch {
+			case t.Name.Local == "node" && parent != nil:
+				parent.Nodes = append(parent.Nodes, attr(t, "name"))
+				err = d.Skip()
+			case t.Name.Local == "replica" && parent != nil:
+				// Bundles contain their resources in `replica` elements, they are flattened into the bundle.
+				var children []*Resource
+				children, err = decodeChildren(d, parent)
+				resources = append(resources, children...)
+			default:
+				err = d.Skip()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// newResource returns a resource with the attributes of the element.
+func newResource(start xml.StartElement, kind ResourceKind, parent *Resource) *Resource {
+	return &Resource{
+		Kind:           kind,
+		ID:             attr(start, "id"),
+		Agent:          attr(start, "resource_agent"),
+		Role:           ParseRole(attr(start, "role")),
+		TargetRole:     ParseRole(attr(start, "target_role")),
+		Active:         attr(start, "active") == "true",
+		Orphaned:       attr(start, "orphaned") == "true",
+		Blocked:        attr(start, "blocked") == "true",
+		Managed:        attr(start, "managed") == "true",
+		Failed:         attr(start, "failed") == "true",
+		FailureIgnored: attr(start, "failure_ignored") == "true",
+		Pending:        attr(start, "pending"),
+		Promotable:     attr(start, "multi_state") == "true" || attr(start, "promotable") == "true",
+		Parent:         parent,
+	}
+}
+
+// attr returns the value of the attribute of the element or an empty string if it is not set.
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,144 @@
+package crm
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseFixture parses the `crm_mon` output saved in testdata.
+func parseFixture(t *testing.T, name string) *ClusterStatus {
+	t.Helper()
+
+	cs, err := ParseStatusFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+func TestResourceTree(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	var top []string
+	for _, r := range cs.Resources {
+		top = append(top, string(r.Kind)+":"+r.ID)
+	}
+	want := []string{"primitive:fence-node1", "group:dwgrp", "clone:pgsql-clone", "clone:ping-clone", "bundle:httpd-bundle"}
+	if !reflect.DeepEqual(top, want) {
+		t.Fatalf("Resources = %v, want %v", top, want)
+	}
+
+	// The resources of bundle replicas are flattened into the bundle.
+	bundle := cs.FindResource("httpd-bundle")
+	var children []string
+	for _, c := range bundle.Children {
+		children = append(children, c.ID)
+		if c.Parent != bundle {
+			t.Errorf("the parent of %v is %v, want httpd-bundle", c.ID, c.Parent)
+		}
+	}
+	want = []string{"httpd-bundle-ip-192.168.122.131", "httpd", "httpd-bundle-podman-0"}
+	if !reflect.DeepEqual(children, want) {
+		t.Errorf("httpd-bundle children = %v, want %v", children, want)
+	}
+
+	if n := len(cs.Resources.Primitives()); n != 10 {
+		t.Errorf("Primitives() returned %v resources, want 10", n)
+	}
+
+	app := cs.FindResource("app")
+	if app == nil || app.Kind != KindPrimitive || app.Parent == nil || app.Parent.ID != "dwgrp" {
+		t.Fatalf("FindResource(app) = %+v, want a primitive of dwgrp", app)
+	}
+	if app.Agent != "systemd:devicewise" || app.Role != "Started" || !app.Active || !app.Managed || app.Failed {
+		t.Errorf("FindResource(app) = %+v", app)
+	}
+	if !app.RunsOn("node1") || app.RunsOn("node2") || app.Node() != "node1" {
+		t.Errorf("app runs on %v, want node1", app.Nodes)
+	}
+
+	if r := cs.FindResource("missing"); r != nil {
+		t.Errorf("FindResource(missing) = %v, want nil", r)
+	}
+}
+
+func TestResourcesOnNode(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	tests := []struct {
+		node string
+		want []string
+	}{
+		{"node1", []string{"vip", "app", "pgsql", "ping", "httpd-bundle-ip-192.168.122.131", "httpd-bundle-podman-0"}},
+		{"node2", []string{"fence-node1", "pgsql", "ping"}},
+		{"httpd-bundle-0", []string{"httpd"}},
+		{"node3", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, r := range cs.ResourcesOnNode(tt.node) {
+			got = append(got, r.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResourcesOnNode(%v) = %v, want %v", tt.node, got, tt.want)
+		}
+	}
+}
+
+func TestGroupLocation(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	if node, err := cs.GroupLocation("dwgrp"); err != nil || node != "node1" {
+		t.Errorf("GroupLocation(dwgrp) = %v, %v, want node1", node, err)
+	}
+
+	// A clone is not a group.
+	if _, err := cs.GroupLocation("ping-clone"); err == nil {
+		t.Error("GroupLocation(ping-clone) error = nil, want an error")
+	}
+
+	cs.FindResource("vip").Active = false
+	if _, err := cs.GroupLocation("dwgrp"); err == nil {
+		t.Error("GroupLocation() of a stopped group error = nil, want an error")
+	}
+}
+
+func TestNodeAttribute(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	if v, ok := cs.NodeAttribute("node2", "pgsql-status"); !ok || v != "HS:sync" {
+		t.Errorf("NodeAttribute(node2, pgsql-status) = %v, %v, want HS:sync", v, ok)
+	}
+	if _, ok := cs.NodeAttribute("node2", "missing"); ok {
+		t.Error("NodeAttribute(node2, missing) found an attribute")
+	}
+
+	if node, ok := cs.NodeWithAttribute("pgsql-status", "PRI"); !ok || node != "node1" {
+		t.Errorf("NodeWithAttribute(pgsql-status, PRI) = %v, %v, want node1", node, ok)
+	}
+	if _, ok := cs.NodeWithAttribute("pgsql-status", "STOP"); ok {
+		t.Error("NodeWithAttribute(pgsql-status, STOP) found a node")
+	}
+}
+
+func TestClusterStatusString(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+	str := cs.String()
+
+	summary := str[strings.Index(str, "Resources Summary:"):]
+	node2 := summary[strings.Index(summary, "Node: node2"):]
+
+	// dwgrp only runs on node1.
+	if !strings.Contains(summary, "    Group: dwgrp\n") {
+		t.Errorf("String() does not list dwgrp:\n%v", summary)
+	}
+	if strings.Contains(node2, "dwgrp") {
+		t.Errorf("String() lists dwgrp under node2:\n%v", node2)
+	}
+	if !strings.Contains(node2, "    Clone: pgsql-clone\n") {
+		t.Errorf("String() does not list pgsql-clone under node2:\n%v", node2)
+	}
+}
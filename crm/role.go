@@ -0,0 +1,45 @@
+package crm
+
+// Role is the role of a resource instance. Legacy role names are normalised by `ParseRole`, so a
+// promoted instance is always `RolePromoted` whether pacemaker reported `Master` or `Promoted`.
+type Role string
+
+// Roles of resources.
+const (
+	RoleUnknown    Role = ""
+	RoleStopped    Role = "Stopped"
+	RoleStarted    Role = "Started"
+	RolePromoted   Role = "Promoted"
+	RoleUnpromoted Role = "Unpromoted"
+	RoleStarting   Role = "Starting"
+	RoleStopping   Role = "Stopping"
+	RolePromoting  Role = "Promoting"
+	RoleDemoting   Role = "Demoting"
+	RoleMigrating  Role = "Migrating"
+)
+
+// legacyRoles maps the role names used before Pacemaker 2.1 to the current ones.
+var legacyRoles = map[string]Role{
+	"Master": RolePromoted,
+	"Slave":  RoleUnpromoted,
+}
+
+// ParseRole returns the role for a role name reported by pacemaker, translating the legacy
+// `Master` and `Slave` names to `RolePromoted` and `RoleUnpromoted`.
+func ParseRole(name string) Role {
+	if r, ok := legacyRoles[name]; ok {
+		return r
+	}
+
+	return Role(name)
+}
+
+// InTransition returns true if the resource is being started, stopped, promoted, demoted or migrated.
+func (r Role) InTransition() bool {
+	switch r {
+	case RoleStarting, RoleStopping, RolePromoting, RoleDemoting, RoleMigrating:
+		return true
+	}
+
+	return false
+}
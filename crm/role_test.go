@@ -0,0 +1,71 @@
+package crm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		want         Role
+		inTransition bool
+	}{
+		{"Master", RolePromoted, false},
+		{"Promoted", RolePromoted, false},
+		{"Slave", RoleUnpromoted, false},
+		{"Unpromoted", RoleUnpromoted, false},
+		{"Started", RoleStarted, false},
+		{"Stopped", RoleStopped, false},
+		{"Promoting", RolePromoting, true},
+		{"Demoting", RoleDemoting, true},
+		{"Migrating", RoleMigrating, true},
+		{"", RoleUnknown, false},
+	}
+
+	for _, tt := range tests {
+		got := ParseRole(tt.name)
+		if got != tt.want {
+			t.Errorf("ParseRole(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if got.InTransition() != tt.inTransition {
+			t.Errorf("ParseRole(%q).InTransition() = %v, want %v", tt.name, got.InTransition(), tt.inTransition)
+		}
+	}
+}
+
+func TestPromotedNode(t *testing.T) {
+	cs := parseFixture(t, "crm_mon.xml")
+
+	if !cs.FindResource("pgsql-clone").Promotable || cs.FindResource("ping-clone").Promotable {
+		t.Error("only pgsql-clone is promotable")
+	}
+
+	tests := []struct {
+		clone   string
+		want    string
+		wantErr error
+	}{
+		{"pgsql-clone", "node1", nil},
+		{"ping-clone", "", ErrNotPromotable},
+		{"dwgrp", "", ErrResourceNotFound},
+		{"missing", "", ErrResourceNotFound},
+	}
+
+	for _, tt := range tests {
+		got, err := cs.PromotedNode(tt.clone)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("PromotedNode(%v) = %v, %v, want %v, %v", tt.clone, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// A demoted primary leaves the clone without a promoted instance.
+	for _, r := range cs.FindResource("pgsql-clone").Primitives() {
+		if r.Promoted() {
+			r.Role = RoleDemoting
+		}
+	}
+	if _, err := cs.PromotedNode("pgsql-clone"); !errors.Is(err, ErrResourceNotRunning) {
+		t.Errorf("PromotedNode() of a demoting clone error = %v, want %v", err, ErrResourceNotRunning)
+	}
+}
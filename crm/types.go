@@ -3,15 +3,30 @@ package crm
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // ClusterStatus contains a summary of the cluster status, cluster nodes, attributes of those nodes, and resources.
-// The status is parsed from the `crm_mon -fA1 --as-xml` command output.
+// The status is parsed from the legacy `crm_mon -fA1 --as-xml` command output or the `pacemaker-result`
+// output of `crm_mon -1 -fA --output-as=xml` (Pacemaker 2.x), both are mapped onto the same structure.
 type ClusterStatus struct {
-	Status     Summary     `xml:"summary"`
-	Nodes      []Node      `xml:"nodes>node"`
-	Attributes []Attribute `xml:"node_attributes>node"`
-	Resources  Resources   `xml:"resources"`
+	// Schema is the schema of the document the status was parsed from.
+	Schema Schema `xml:"-"`
+	// Version is the Pacemaker version of legacy documents, APIVersion the api version of `pacemaker-result` documents.
+	Version    string `xml:"version,attr"`
+	APIVersion string `xml:"api-version,attr"`
+	// Result is the result of the command that produced a `pacemaker-result` document.
+	Result struct {
+		Code    int    `xml:"code,attr"`
+		Message string `xml:"message,attr"`
+	} `xml:"status"`
+
+	Status     Summary       `xml:"summary"`
+	Nodes      []Node        `xml:"nodes>node"`
+	Attributes []Attribute   `xml:"node_attributes>node"`
+	Resources  Resources     `xml:"resources"`
+	History    []NodeHistory `xml:"node_history>node"`
+	Failures   []Failure     `xml:"failures>failure"`
 }
 
 func (cs ClusterStatus) String() string {
@@ -33,31 +48,48 @@ func (cs ClusterStatus) String() string {
 	for _, n := range cs.Nodes {
 		str += fmt.Sprintf("  Node: %v\n", n.Name)
 
-		for _, sr := range cs.Resources.StandAlone {
-			if sr.Node.Name == n.Name {
-				str += sr.String()
+		for _, r := range cs.Resources {
+			if r.Kind == KindPrimitive {
+				if r.RunsOn(n.Name) {
+					str += r.String()
+				}
+				continue
 			}
-		}
 
-		for _, g := range cs.Resources.Groups {
-			str += fmt.Sprintf("    Group: %v\n", g.Name)
-			for _, gr := range g.Resources {
-				if gr.Node.Name == n.Name {
-					str += gr.String()
+			// Groups, clones and bundles are only listed under the nodes running some of their resources.
+			var children string
+			r.Walk(func(child *Resource) {
+				if child.Kind == KindPrimitive && child.RunsOn(n.Name) {
+					children += child.String()
 				}
+			})
+			if children != "" {
+				kind := string(r.Kind)
+				str += fmt.Sprintf("    %v: %v\n", strings.ToUpper(kind[:1])+kind[1:], r.ID) + children
 			}
 		}
+	}
 
-		for _, c := range cs.Resources.Cloned {
-			str += fmt.Sprintf("    Clone: %v\n", c.Name)
-			for _, cr := range c.Resources {
-				if cr.Node.Name == n.Name {
-					str += cr.String()
-				}
+	var failCounts string
+	for _, n := range cs.History {
+		for _, h := range n.Resources {
+			if h.FailCount > 0 {
+				failCounts += fmt.Sprintf("    %v on %v: fail-count=%v migration-threshold=%v last-failure=%v\n", h.ID, n.Node, h.FailCount, h.MigrationThreshold, h.LastFailure)
 			}
 		}
 	}
 
+	if failCounts != "" {
+		str += "\nFail Counts:\n" + failCounts
+	}
+
+	if len(cs.Failures) > 0 {
+		str += "\nFailed Actions:\n"
+		for _, f := range cs.Failures {
+			str += f.String()
+		}
+	}
+
 	return str
 }
 
@@ -74,13 +106,16 @@ type Summary struct {
 		Number int `xml:"number,attr"`
 	} `xml:"nodes_configured"`
 	ResourcesConfigured struct {
-		Number int `xml:"number,attr"`
+		Number   int `xml:"number,attr"`
+		Disabled int `xml:"disabled,attr"`
+		Blocked  int `xml:"blocked,attr"`
 	} `xml:"resources_configured"`
 	Options struct {
 		StonithEnabled   bool   `xml:"stonith-enabled,attr"`
 		SymmetricCluster bool   `xml:"symmetric-cluster,attr"`
 		NoQuorumPolicy   string `xml:"no-quorum-policy,attr"`
 		MaintenanceMode  bool   `xml:"maintenance-mode,attr"`
+		StopAllResources bool   `xml:"stop-all-resources,attr"`
 	} `xml:"cluster_options"`
 }
 
@@ -89,13 +124,14 @@ func (s Summary) String() string {
   Stack Type           : %v
   Designated Controller: [ Node: %v | HasQuorum: %v ]
   Nodes Configured     : %v
-  Resources Configured : %v
+  Resources Configured : %v [ Disabled: %v | Blocked: %v ]
   Cluster Options      : [ Stonith Enabled: %v | Symmetric Cluster: %v | No Quorum Policy: %v | Maintenance Mode: %v ] 
 `
 
 	str = fmt.Sprintf(str, s.Stack.Type,
 		s.DesignatedController.Node, s.DesignatedController.Quorum,
-		s.NodesConfigured.Number, s.ResourcesConfigured.Number, s.Options.StonithEnabled,
+		s.NodesConfigured.Number, s.ResourcesConfigured.Number, s.ResourcesConfigured.Disabled,
+		s.ResourcesConfigured.Blocked, s.Options.StonithEnabled,
 		s.Options.SymmetricCluster, s.Options.NoQuorumPolicy, s.Options.MaintenanceMode)
 
 	return str
@@ -110,18 +146,24 @@ type Node struct {
 	Pending     bool   `xml:"pending,attr"`
 	Unclean     bool   `xml:"unclean,attr"`
 	Shutdown    bool   `xml:"shutdown,attr"`
+	IsDC        bool   `xml:"is_dc,attr"`
+	// Type is the type of the node, e.g. `member` or `remote`.
+	Type             string `xml:"type,attr"`
+	ResourcesRunning int    `xml:"resources_running,attr"`
 }
 
 func (n Node) String() string {
 	str := fmt.Sprintf("Node: %s\n", n.Name)
 
 	str += fmt.Sprintln("  Status:")
+	var status []string
 	v := reflect.ValueOf(n)
 	for i := 0; i < v.NumField(); i++ {
 		if v.Field(i).Type().Name() == "bool" && v.Field(i).Interface() == true {
-			str += fmt.Sprintf("    %s: %v", v.Type().Field(i).Name, v.Field(i).Interface())
+			status = append(status, fmt.Sprintf("    %s: %v", v.Type().Field(i).Name, v.Field(i).Interface()))
 		}
 	}
+	str += strings.Join(status, "\n")
 
 	return str
 }
@@ -144,90 +186,3 @@ func (a Attribute) String() string {
 
 	return str
 }
-
-// Resources contains collections of standalone resources, grouped resources, and cloned resources.
-type Resources struct {
-	StandAlone []StandAloneResource `xml:"resource"`
-	Groups     []ResourceGroup      `xml:"group"`
-	Cloned     []ResourceClone      `xml:"clone"`
-}
-
-// StandAloneResource is a structure containing resources of the cluster that are not grouped or cloned.
-// This structure contains not only various resource properties like the status and agent but also the node
-// that the resource is running on.
-type StandAloneResource struct {
-	Node struct {
-		Name string `xml:"name,attr"`
-	} `xml:"node"`
-	Name    string `xml:"id,attr"`
-	Agent   string `xml:"resource_agent,attr"`
-	Role    string `xml:"role,attr"`
-	Active  bool   `xml:"active,attr"`
-	Blocked bool   `xml:"blocked,attr"`
-	Managed bool   `xml:"managed,attr"`
-	Failed  bool   `xml:"failed,attr"`
-}
-
-func (r StandAloneResource) String() string {
-	fmtString := "      [ Name: %v | Agent: %v | Role: %v | Active: %v | Blocked: %v | Managed: %v | Failed: %v ]\n"
-
-	str := fmt.Sprintf(fmtString, r.Name, r.Agent, r.Role, r.Active, r.Blocked, r.Managed, r.Failed)
-
-	return str
-}
-
-// ResourceGroup contains the resource group name along with a collection of GroupedResources.
-type ResourceGroup struct {
-	Name      string            `xml:"id,attr"`
-	Resources []GroupedResource `xml:"resource"`
-}
-
-// GroupedResource contains the same general structure as a StandAloneResource.
-type GroupedResource struct {
-	Node struct {
-		Name string `xml:"name,attr"`
-	} `xml:"node"`
-	Name    string `xml:"id,attr"`
-	Agent   string `xml:"resource_agent,attr"`
-	Role    string `xml:"role,attr"`
-	Active  bool   `xml:"active,attr"`
-	Blocked bool   `xml:"blocked,attr"`
-	Managed bool   `xml:"managed,attr"`
-	Failed  bool   `xml:"failed,attr"`
-}
-
-func (r GroupedResource) String() string {
-	fmtString := "      [ Name: %v | Agent: %v | Role: %v | Active: %v | Blocked: %v | Managed: %v | Failed: %v ]\n"
-
-	str := fmt.Sprintf(fmtString, r.Name, r.Agent, r.Role, r.Active, r.Blocked, r.Managed, r.Failed)
-
-	return str
-}
-
-// ResourceClone contains the resource clone name along with a collection of ClonedResources.
-type ResourceClone struct {
-	Name      string           `xml:"id,attr"`
-	Resources []ClonedResource `xml:"resource"`
-}
-
-// ClonedResource contains the same general structure as a StandAloneResource.
-type ClonedResource struct {
-	Node struct {
-		Name string `xml:"name,attr"`
-	} `xml:"node"`
-	Name    string `xml:"id,attr"`
-	Agent   string `xml:"resource_agent,attr"`
-	Role    string `xml:"role,attr"`
-	Active  bool   `xml:"active,attr"`
-	Blocked bool   `xml:"blocked,attr"`
-	Managed bool   `xml:"managed,attr"`
-	Failed  bool   `xml:"failed,attr"`
-}
-
-func (r ClonedResource) String() string {
-	fmtString := "      [ Name: %v | Agent: %v | Role: %v | Active: %v | Blocked: %v | Managed: %v | Failed: %v ]\n"
-
-	str := fmt.Sprintf(fmtString, r.Name, r.Agent, r.Role, r.Active, r.Blocked, r.Managed, r.Failed)
-
-	return str
-}
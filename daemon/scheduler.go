@@ -0,0 +1,344 @@
+// Package daemon runs the failovers of several clusters at their configured times from a long running process,
+// replacing a cron entry per cluster. The time of the last scheduled run of each job is kept in a state file so a
+// run missed while the daemon was not running is caught up within a grace period or reported as missed. The
+// daemon supports the sd_notify readiness, reload and watchdog protocol of systemd.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/schedule"
+)
+
+// DefaultGrace is used when `Config.Grace` is not set.
+const DefaultGrace = time.Hour
+
+// maxMissed bounds the number of missed runs passed to `Scheduler.Missed`, e.g. after the daemon was stopped for months.
+const maxMissed = 100
+
+// Job runs the failover of a profile at the times of its cron expression. The schedule of the profile then
+// decides what the run does, so a job usually runs every day.
+type Job struct {
+	// Name identifies the job in the state file, the name of its profile.
+	Name string
+	Cron schedule.Cron
+	// Spec is the cron expression, or time of day, the job was configured with.
+	Spec string
+}
+
+// Config is the configuration of the scheduler.
+type Config struct {
+	Jobs []Job
+	// Location is the time zone the cron expressions are evaluated in.
+	Location *time.Location
+	// Grace is how late a missed run may still be performed, `DefaultGrace` by default. Older runs are reported as missed.
+	Grace     time.Duration
+	StateFile string
+	// Commit, if set, is called once the state file is read, before the configuration replaces the
+	// previous one. Settings the jobs depend on are only committed by it so a rejected reload leaves them alone.
+	Commit func()
+}
+
+// Scheduler runs the jobs at their times.
+type Scheduler struct {
+	// Load returns the configuration. It is called on start and on reload.
+	Load func() (Config, error)
+	// RunJob runs a job for its scheduled time and returns the outcome of the run.
+	RunJob func(ctx context.Context, job Job, scheduled time.Time) string
+	// Missed reports the runs of a job that were missed and will not be performed.
+	Missed func(job Job, scheduled []time.Time)
+	// Logf logs the activity of the scheduler.
+	Logf func(format string, args ...interface{})
+	// Now returns the current time, `time.Now` if it is nil.
+	Now func() time.Time
+
+	config Config
+	state  State
+	// watchdog is the interval of the systemd watchdog, zero if it is not enabled.
+	watchdog time.Duration
+}
+
+// NextRun is the time of the next run of a job.
+type NextRun struct {
+	Job  Job
+	Time time.Time
+}
+
+// Run loads the configuration and runs the jobs until the context is done. The configuration is loaded
+// again when a value is received from `reload`. An invalid configuration is an error on start, on reload
+// it is logged and the previous configuration is kept.
+//
+// The systemd watchdog is pinged by the loop itself, which wakes up at least every half watchdog interval, so
+// a hung loop is detected. It is pinged from a separate goroutine only while a job runs, see `keepAlive`.
+func (s *Scheduler) Run(ctx context.Context, reload <-chan os.Signal) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	s.watchdog = WatchdogInterval()
+	s.notify(NotifyReady)
+
+	for {
+		s.ping()
+		s.runDue(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		next := s.NextRuns(s.now())
+		s.saveNextRuns(next)
+
+		// The clock is checked every minute so a change of the system time, or a suspended host, does not
+		// delay the runs.
+		wait := time.Minute
+		if s.watchdog > 0 && s.watchdog/2 < wait {
+			wait = s.watchdog / 2
+		}
+		if len(next) > 0 {
+			s.notify("STATUS=Next run: " + describe(next[0]))
+			if until := next[0].Time.Sub(s.now()); until < wait {
+				wait = until
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-reload:
+			timer.Stop()
+			s.notify(NotifyReloading)
+			if err := s.load(); err != nil {
+				s.logf("Failed to reload the configuration, the previous configuration is kept: %v", err)
+			} else {
+				s.logf("Configuration reloaded")
+			}
+			s.notify(NotifyReady)
+		case <-timer.C:
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	s.notify(NotifyStopping)
+	return nil
+}
+
+// NextRuns returns the next run of every job after `t`, the earliest first.
+func (s *Scheduler) NextRuns(t time.Time) []NextRun {
+	var runs []NextRun
+	for _, job := range s.config.Jobs {
+		if next := job.Cron.Next(t.In(s.config.Location)); !next.IsZero() {
+			runs = append(runs, NextRun{Job: job, Time: next})
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Time.Before(runs[j].Time) })
+	return runs
+}
+
+// State returns the state of the jobs.
+func (s *Scheduler) State() State {
+	return s.state
+}
+
+// load loads the configuration and the state file.
+func (s *Scheduler) load() error {
+	config, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.Grace <= 0 {
+		config.Grace = DefaultGrace
+	}
+
+	state, err := LoadState(config.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the state file %v: %v", config.StateFile, err)
+	}
+
+	if config.Commit != nil {
+		config.Commit()
+	}
+	s.config, s.state = config, state
+
+	for _, run := range s.NextRuns(s.now()) {
+		s.logf("Next run of %v", describe(run))
+	}
+
+	return nil
+}
+
+// runDue runs the jobs whose scheduled time has passed since their last run. Only the last missed run of a job
+// is performed, if it is within the grace period, the others are reported as missed.
+func (s *Scheduler) runDue(ctx context.Context) {
+	for _, job := range s.config.Jobs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := s.now().In(s.config.Location)
+		js := s.state.Jobs[job.Name]
+
+		// Nothing was missed before the first start of the job.
+		if js.LastScheduled.IsZero() {
+			js.LastScheduled = now
+			s.state.Jobs[job.Name] = js
+			s.save()
+			continue
+		}
+
+		// Only the last runs are kept, the count tells how many were missed.
+		var due []time.Time
+		count := 0
+		for t := job.Cron.Next(js.LastScheduled.In(s.config.Location)); !t.IsZero() && !t.After(now); t = job.Cron.Next(t) {
+			due = append(due, t)
+			if len(due) > maxMissed+1 {
+				due = due[1:]
+			}
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+
+		scheduled := due[len(due)-1]
+		missed := due[:len(due)-1]
+		missedCount := count - 1
+		late := now.Sub(scheduled)
+		if late > s.config.Grace {
+			missed, missedCount = due, count
+		}
+		if len(missed) > maxMissed {
+			missed = missed[len(missed)-maxMissed:]
+		}
+
+		// The run is recorded before it starts so a crash during a failover never repeats it.
+		js.LastScheduled = scheduled
+		s.state.Jobs[job.Name] = js
+		s.save()
+
+		if len(missed) > 0 {
+			s.logf("Missed %v run(s) of %v, the last one at %v", missedCount, job.Name, missed[len(missed)-1].Format(time.RFC1123))
+			if s.Missed != nil {
+				s.Missed(job, missed)
+			}
+		}
+
+		if late > s.config.Grace {
+			continue
+		}
+
+		if late >= time.Minute {
+			s.logf("Catching up the run of %v scheduled at %v", job.Name, scheduled.Format(time.RFC1123))
+		} else {
+			s.logf("Running %v", job.Name)
+		}
+
+		js.LastRun = s.now()
+		stop := s.keepAlive()
+		js.LastOutcome = s.RunJob(ctx, job, scheduled)
+		stop()
+		s.state.Jobs[job.Name] = js
+		s.save()
+
+		s.logf("Run of %v finished: %v", job.Name, js.LastOutcome)
+	}
+}
+
+// saveNextRuns records the next runs in the state file.
+func (s *Scheduler) saveNextRuns(next []NextRun) {
+	changed := false
+	for _, run := range next {
+		js := s.state.Jobs[run.Job.Name]
+		if !js.NextRun.Equal(run.Time) {
+			js.NextRun = run.Time
+			s.state.Jobs[run.Job.Name] = js
+			changed = true
+		}
+	}
+
+	if changed {
+		s.save()
+	}
+}
+
+func (s *Scheduler) save() {
+	if err := s.state.Save(s.config.StateFile); err != nil {
+		s.logf("Failed to write the state file %v: %v", s.config.StateFile, err)
+	}
+}
+
+// ping pings the systemd watchdog, if it is enabled.
+func (s *Scheduler) ping() {
+	if s.watchdog > 0 {
+		s.notify(NotifyWatchdog)
+	}
+}
+
+// keepAlive pings the systemd watchdog at half its interval until the returned function is called. A failover
+// usually takes longer than the watchdog interval, the timeouts of its profile bound it instead.
+func (s *Scheduler) keepAlive() (stop func()) {
+	if s.watchdog <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(s.watchdog / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.ping()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func (s *Scheduler) notify(state string) {
+	if _, err := Notify(state); err != nil {
+		s.logf("Failed to notify systemd: %v", err)
+	}
+}
+
+func (s *Scheduler) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+
+	return time.Now()
+}
+
+func (s *Scheduler) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// describe returns the name of the job and the time of the run.
+func describe(run NextRun) string {
+	return fmt.Sprintf("%v at %v (%v)", run.Job.Name, run.Time.Format("Mon 2006-01-02 15:04 MST"), strings.TrimSpace(run.Job.Spec))
+}
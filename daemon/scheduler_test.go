@@ -0,0 +1,235 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/schedule"
+)
+
+// at returns the time of day on the date in October 2026, in UTC.
+func at(day, hour, min int) time.Time {
+	return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	cron, err := schedule.ParseCron("30 4 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := Job{Name: "pkm", Cron: cron, Spec: "04:30"}
+
+	tests := []struct {
+		name string
+		// lastScheduled is the last scheduled run in the state file, none if zero.
+		lastScheduled time.Time
+		now           time.Time
+		// ran is the scheduled time the job was run for, it did not run if zero.
+		ran    time.Time
+		missed []time.Time
+		// wantLast is the last scheduled run in the state file after `runDue`.
+		wantLast time.Time
+	}{
+		{
+			name:     "first start",
+			now:      at(17, 5, 0),
+			wantLast: at(17, 5, 0),
+		},
+		{
+			name:          "nothing due",
+			lastScheduled: at(17, 4, 30),
+			now:           at(17, 5, 0),
+			wantLast:      at(17, 4, 30),
+		},
+		{
+			name:          "on time",
+			lastScheduled: at(16, 4, 30),
+			now:           at(17, 4, 30),
+			ran:           at(17, 4, 30),
+			wantLast:      at(17, 4, 30),
+		},
+		{
+			name:          "caught up within the grace period",
+			lastScheduled: at(16, 4, 30),
+			now:           at(17, 5, 15),
+			ran:           at(17, 4, 30),
+			wantLast:      at(17, 4, 30),
+		},
+		{
+			name:          "missed beyond the grace period",
+			lastScheduled: at(16, 4, 30),
+			now:           at(17, 6, 0),
+			missed:        []time.Time{at(17, 4, 30)},
+			wantLast:      at(17, 4, 30),
+		},
+		{
+			name:          "older runs missed",
+			lastScheduled: at(13, 4, 30),
+			now:           at(17, 4, 45),
+			ran:           at(17, 4, 30),
+			missed:        []time.Time{at(14, 4, 30), at(15, 4, 30), at(16, 4, 30)},
+			wantLast:      at(17, 4, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+
+			state := State{Jobs: make(map[string]JobState)}
+			if !tt.lastScheduled.IsZero() {
+				state.Jobs[job.Name] = JobState{LastScheduled: tt.lastScheduled}
+			}
+			if err := state.Save(path); err != nil {
+				t.Fatal(err)
+			}
+
+			var ran time.Time
+			var missed []time.Time
+			s := &Scheduler{
+				Load: func() (Config, error) {
+					return Config{Jobs: []Job{job}, Location: time.UTC, StateFile: path}, nil
+				},
+				RunJob: func(ctx context.Context, j Job, scheduled time.Time) string {
+					if !ran.IsZero() {
+						t.Errorf("RunJob() called twice")
+					}
+					ran = scheduled
+					return "Success"
+				},
+				Missed: func(j Job, scheduled []time.Time) {
+					missed = append(missed, scheduled...)
+				},
+				Now: func() time.Time { return tt.now },
+			}
+			if err := s.load(); err != nil {
+				t.Fatal(err)
+			}
+
+			s.runDue(context.Background())
+
+			if !ran.Equal(tt.ran) {
+				t.Errorf("runDue() ran the job for %v, want %v", ran, tt.ran)
+			}
+			if !reflect.DeepEqual(missed, tt.missed) {
+				t.Errorf("runDue() reported %v as missed, want %v", missed, tt.missed)
+			}
+
+			// The state file is up to date after every run.
+			saved, err := LoadState(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			js := saved.Jobs[job.Name]
+			if !js.LastScheduled.Equal(tt.wantLast) {
+				t.Errorf("runDue() saved the last scheduled run %v, want %v", js.LastScheduled, tt.wantLast)
+			}
+			if !tt.ran.IsZero() && (js.LastOutcome != "Success" || !js.LastRun.Equal(tt.now)) {
+				t.Errorf("runDue() saved the last run %v with outcome %q, want %v with outcome Success", js.LastRun, js.LastOutcome, tt.now)
+			}
+		})
+	}
+}
+
+func TestSchedulerLoadCommit(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "state.json")
+
+	var committed []string
+	config := func(name, stateFile string) Config {
+		return Config{
+			Jobs:      []Job{{Name: name}},
+			StateFile: stateFile,
+			Commit:    func() { committed = append(committed, name) },
+		}
+	}
+
+	next := config("pkm", valid)
+	s := &Scheduler{Load: func() (Config, error) { return next, nil }}
+
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The state file of the reloaded configuration is a directory and cannot be read.
+	next = config("dw", dir)
+	if err := s.load(); err == nil {
+		t.Errorf("load() = nil, want an error for an unreadable state file")
+	}
+
+	if want := []string{"pkm"}; !reflect.DeepEqual(committed, want) {
+		t.Errorf("load() committed %v, want %v", committed, want)
+	}
+	if name := s.config.Jobs[0].Name; name != "pkm" {
+		t.Errorf("load() kept the jobs of %v, want pkm", name)
+	}
+}
+
+func TestSchedulerRunDueMaxMissed(t *testing.T) {
+	cron, err := schedule.ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := Job{Name: "dw", Cron: cron, Spec: "0 * * * *"}
+
+	var missed []time.Time
+	s := &Scheduler{
+		RunJob: func(ctx context.Context, j Job, scheduled time.Time) string { return "Success" },
+		Missed: func(j Job, scheduled []time.Time) { missed = scheduled },
+		Now:    func() time.Time { return at(17, 12, 0) },
+		config: Config{Jobs: []Job{job}, Location: time.UTC, Grace: DefaultGrace, StateFile: filepath.Join(t.TempDir(), "state.json")},
+		// The daemon was stopped for a month.
+		state: State{Jobs: map[string]JobState{job.Name: {LastScheduled: time.Date(2026, 9, 17, 12, 0, 0, 0, time.UTC)}}},
+	}
+
+	s.runDue(context.Background())
+
+	if len(missed) != maxMissed {
+		t.Fatalf("runDue() reported %v missed runs, want %v", len(missed), maxMissed)
+	}
+	if last := missed[len(missed)-1]; !last.Equal(at(17, 11, 0)) {
+		t.Errorf("runDue() reported the last missed run at %v, want %v", last, at(17, 11, 0))
+	}
+}
+
+func TestSchedulerKeepAlive(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	s := &Scheduler{watchdog: 20 * time.Millisecond}
+	stop := s.keepAlive()
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("keepAlive() did not ping the watchdog: %v", err)
+	}
+	if got := string(buf[:n]); got != NotifyWatchdog {
+		t.Errorf("keepAlive() sent %q, want %q", got, NotifyWatchdog)
+	}
+
+	// The pings sent before `stop` returned are already queued on the socket, none may follow.
+	conn.SetReadDeadline(time.Now())
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	conn.SetReadDeadline(time.Now())
+	if _, err := conn.Read(buf); err == nil {
+		t.Errorf("keepAlive() pinged the watchdog after it was stopped")
+	}
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is persisted between the runs of the daemon to catch up the runs missed while it was not running.
+type State struct {
+	Jobs map[string]JobState `json:"jobs"`
+}
+
+// JobState is the state of a job.
+type JobState struct {
+	// LastScheduled is the scheduled time of the last run that was performed or reported as missed.
+	LastScheduled time.Time `json:"lastScheduled"`
+	// LastRun is the time the last run started, LastOutcome its outcome.
+	LastRun     time.Time `json:"lastRun"`
+	LastOutcome string    `json:"lastOutcome,omitempty"`
+	// NextRun is the time of the next run.
+	NextRun time.Time `json:"nextRun"`
+}
+
+// LoadState reads the state file. An empty state is returned if the file does not exist.
+func LoadState(path string) (State, error) {
+	state := State{Jobs: make(map[string]JobState)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, err
+	}
+
+	if state.Jobs == nil {
+		state.Jobs = make(map[string]JobState)
+	}
+
+	return state, nil
+}
+
+// Save writes the state file. The file is replaced atomically so a crash never leaves a truncated state.
+func (s State) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofailover", "state.json")
+
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() of a missing file error = %v", err)
+	}
+	if state.Jobs == nil || len(state.Jobs) != 0 {
+		t.Fatalf("LoadState() of a missing file = %v, want an empty state", state)
+	}
+
+	at := time.Date(2026, 10, 17, 4, 30, 0, 0, time.UTC)
+	state.Jobs["pkm"] = JobState{
+		LastScheduled: at,
+		LastRun:       at.Add(time.Second),
+		LastOutcome:   "Success",
+		NextRun:       at.AddDate(0, 0, 1),
+	}
+
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, state) {
+		t.Errorf("LoadState() = %v, want %v", got, state)
+	}
+
+	// No temporary file is left next to the state file.
+	files, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) > 0 {
+		t.Errorf("Save() left %v", files)
+	}
+}
@@ -0,0 +1,44 @@
+// Package failover contains the engine used to perform scheduled failovers of pacemaker clusters.
+// Each application only has to implement the `Cluster` interface, the engine takes care of evaluating
+// the schedule, checking the health of the cluster before and after the failover and sending notifications.
+package failover
+
+import (
+	"context"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/probe"
+)
+
+// Cluster is implemented by every application that can be failed over by the engine.
+// Implementations only need to know how to find the current primary node and how to
+// move the primary role to the other node.
+type Cluster interface {
+	// Name returns the human readable name of the cluster used in notifications, e.g. `PKM database`.
+	Name() string
+	// PrimaryNode returns the node currently acting as the primary of the cluster.
+	// An error is returned if no primary node can be found.
+	PrimaryNode(cs crm.ClusterStatus) (string, error)
+	// Failover runs the commands required to move the primary role to the other node.
+	// The commands should be stopped when the context is done.
+	Failover(ctx context.Context, cs crm.ClusterStatus) error
+}
+
+// Cleaner is implemented by clusters that need to be cleaned up when a failover is interrupted,
+// e.g. clearing the location constraint left behind by `pcs resource move`.
+type Cleaner interface {
+	Cleanup(ctx context.Context) error
+}
+
+// PreChecker is implemented by clusters with checks of their own that must pass before a failover,
+// e.g. the replication state of a database. The findings are added to the health report.
+type PreChecker interface {
+	PreCheck(ctx context.Context, cs crm.ClusterStatus) health.Report
+}
+
+// Prober is implemented by clusters checking that the application works after a failover, e.g. that its
+// HTTP endpoint answers. A failed probe fails the failover.
+type Prober interface {
+	Probe(ctx context.Context) probe.Results
+}
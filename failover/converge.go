@@ -0,0 +1,113 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Default convergence settings used when a profile does not set them.
+const (
+	DefaultConvergeInterval = 5 * time.Second
+	DefaultConvergeTimeout  = 5 * time.Minute
+)
+
+// Convergence contains the settings used to wait for the cluster to converge after a failover.
+type Convergence struct {
+	// Interval is the time between two polls of the cluster status.
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout is the overall deadline. `ErrNotConverged` is returned once it has passed.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// SkipDCCheck disables checking that the DC is idle with `crmadmin -S`.
+	SkipDCCheck bool `mapstructure:"skipDCCheck"`
+}
+
+// Converger polls the cluster until it has converged. The cluster has converged when the caller's
+// condition is met, no resources are in transition and the DC is idle.
+type Converger struct {
+	Convergence
+	// Status returns the current status of the cluster.
+	Status func(ctx context.Context) (crm.ClusterStatus, error)
+	// Executor runs `crmadmin -S` to check that the DC is idle.
+	Executor runner.Executor
+}
+
+// Converger.Wait() polls the cluster until `ready` returns true and the cluster is idle. `ready` returns a
+// description of what it is waiting for when it returns false. The last status pulled is returned.
+func (c *Converger) Wait(ctx context.Context, ready func(cs crm.ClusterStatus) (bool, string)) (crm.ClusterStatus, error) {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultConvergeInterval
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultConvergeTimeout
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var cs crm.ClusterStatus
+	var reason string
+	for {
+		var err error
+		cs, err = c.Status(ctx)
+		if err != nil {
+			reason = err.Error()
+		} else if ok, why := ready(cs); !ok {
+			reason = why
+		} else if why := inTransition(cs); why != "" {
+			reason = why
+		} else if why := c.dcBusy(ctx, cs); why != "" {
+			reason = why
+		} else {
+			return cs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return cs, ctx.Err()
+		case <-deadline.C:
+			return cs, fmt.Errorf("%w after %v: %v", ErrNotConverged, timeout, reason)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// inTransition returns a description of the first resource found in transition or an empty string.
+func inTransition(cs crm.ClusterStatus) string {
+	for _, r := range cs.Resources.Primitives() {
+		if r.Pending != "" {
+			return fmt.Sprintf("resource %v has a pending %v operation", r.ID, r.Pending)
+		}
+		if r.Role.InTransition() {
+			return fmt.Sprintf("resource %v is %v", r.ID, strings.ToLower(string(r.Role)))
+		}
+	}
+
+	return ""
+}
+
+// dcBusy returns a description of the DC state if it is not idle or an empty string.
+func (c *Converger) dcBusy(ctx context.Context, cs crm.ClusterStatus) string {
+	if c.SkipDCCheck || c.Executor == nil {
+		return ""
+	}
+
+	dc := cs.Status.DesignatedController.Node
+	if dc == "" {
+		return "the cluster has no DC"
+	}
+
+	if findings := health.CheckDC(ctx, c.Executor, cs).Findings; len(findings) > 0 {
+		return findings[0].Message
+	}
+
+	return ""
+}
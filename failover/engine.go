@@ -0,0 +1,539 @@
+package failover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/probe"
+	"github.com/KalebHawkins/gofailover/schedule"
+)
+
+// DefaultCleanupTimeout is used when `Engine.CleanupTimeout` is not set.
+const DefaultCleanupTimeout = 2 * time.Minute
+
+// Steps of a run. The step in progress is reported if a run is aborted.
+const (
+	StepPreCheck  = "pre-failover health check"
+	StepFailover  = "failover"
+	StepPostCheck = "post-failover health check"
+	StepProbes    = "post-failover probes"
+	StepRollback  = "rollback"
+)
+
+// Engine drives the failover of a `Cluster`. It evaluates the schedule, performs the health checks
+// before and after the failover, runs the failover itself and sends the notifications.
+type Engine struct {
+	Cluster Cluster
+
+	// ExpectedPrimaryNode is the node that should normally be running as the primary.
+	ExpectedPrimaryNode string
+	// Schedule decides the action of the run from the date, `schedule.Default` is used if it is nil.
+	Schedule *schedule.Calendar
+	// Now returns the date and time the schedule is evaluated for, in the time zone of the schedule.
+	// `time.Now` is used if it is nil.
+	Now func() time.Time
+	// Override performs the failover regardless of the date or the current primary node.
+	Override bool
+	// Rollback fails the cluster back to the original primary node, once, if the post-failover health
+	// check or a probe fails.
+	Rollback bool
+	// CleanupTimeout bounds the cleanup performed when a run is aborted.
+	CleanupTimeout time.Duration
+	// Lock elects the node performing the run when the tool runs on every node of the cluster. It is called at the
+	// start of the run and returns a function releasing the lock. If it returns an error wrapping `ErrNotLeader`
+	// the run is skipped. No lock is taken if it is nil.
+	Lock func(ctx context.Context) (func(), error)
+
+	// Status returns the current status of the cluster.
+	Status func(ctx context.Context) (crm.ClusterStatus, error)
+	// HealthCheck evaluates the health of the cluster. Only critical findings block the failover,
+	// every finding is included in the notifications.
+	HealthCheck func(ctx context.Context, cs crm.ClusterStatus) health.Report
+	// Policy is applied to the findings of the health checks, including the ones of a `PreChecker`.
+	Policy health.Policy
+	// Notify sends a notification message, usually by email.
+	Notify func(msg string)
+	// ErrorMessage, SuccessMessage, AbortedMessage, RolledBackMessage, RollbackFailedMessage and SkippedMessage are
+	// `text/template` templates used for the notifications. The `Default*Message` templates are used if they are empty.
+	ErrorMessage          string
+	SuccessMessage        string
+	AbortedMessage        string
+	RolledBackMessage     string
+	RollbackFailedMessage string
+	SkippedMessage        string
+
+	step               string
+	decision           schedule.Decision
+	report             health.Report
+	probes             probe.Results
+	clusterStatus      crm.ClusterStatus
+	currentPrimaryNode string
+}
+
+// Run performs the failover if the schedule requires it. An error is returned, after a notification
+// has been sent, if the cluster is unhealthy or the failover fails. The outcome tells which step failed.
+//
+// The run lock is acquired first, if another node holds it nothing is done and `OutcomeNotLeader` is returned.
+// If the context is cancelled the step in progress is stopped, the cluster is cleaned up if the failover
+// had already started, an "aborted" notification is sent and an error wrapping `ErrAborted` is returned.
+func (e *Engine) Run(ctx context.Context) (Outcome, error) {
+	e.step = ""
+	e.report = health.Report{}
+	e.probes = nil
+
+	if e.Lock != nil {
+		release, err := e.Lock(ctx)
+		switch {
+		case err != nil && errors.Is(err, ErrNotLeader):
+			return OutcomeNotLeader, err
+		case err != nil && ctx.Err() != nil:
+			return OutcomeAborted, fmt.Errorf("%w while acquiring the run lock: %v", ErrAborted, err)
+		case err != nil:
+			return OutcomeError, fmt.Errorf("failed to acquire the run lock: %w", err)
+		}
+		defer release()
+	}
+
+	performed, err := e.run(ctx)
+	if err != nil && ctx.Err() != nil {
+		return OutcomeAborted, e.abort(ctx.Err())
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRolledBack):
+			return OutcomeRolledBack, err
+		case errors.Is(err, ErrRollbackFailed):
+			return OutcomeRollbackFailed, err
+		}
+
+		switch e.step {
+		case StepPreCheck:
+			return OutcomePreCheckFailed, err
+		case StepFailover:
+			return OutcomeFailoverFailed, err
+		case StepPostCheck, StepProbes:
+			return OutcomePostCheckFailed, err
+		}
+		return OutcomeError, err
+	}
+
+	if !performed {
+		return OutcomeSkipped, nil
+	}
+
+	return OutcomeSuccess, nil
+}
+
+// run returns true if a failover was performed.
+func (e *Engine) run(ctx context.Context) (bool, error) {
+	perform, reason, cs, err := e.decide(ctx)
+	if err != nil {
+		return false, e.handleError(ctx, err, cs)
+	}
+
+	if !perform {
+		if e.decision.Blackout != "" {
+			e.handleSkipped(reason)
+		}
+		return false, nil
+	}
+
+	return true, e.failover(ctx)
+}
+
+// decide evaluates the schedule and runs the pre-failover health check. It returns true if a failover has to
+// be performed along with the reason of the decision. The status of the cluster is returned for notifications.
+func (e *Engine) decide(ctx context.Context) (bool, string, crm.ClusterStatus, error) {
+	// If the override switch is flipped on then perform a failover regardless of the day
+	// of the week or which node is the current primary. This will not run if health checks fail.
+	if e.Override {
+		cs, err := e.check(ctx, StepPreCheck)
+		if err != nil {
+			return false, "", cs, err
+		}
+		return true, "the override flag is set", cs, nil
+	}
+
+	cal := e.Schedule
+	if cal == nil {
+		cal, _ = schedule.Default("").Compile()
+	}
+
+	now := time.Now
+	if e.Now != nil {
+		now = e.Now
+	}
+
+	today := now()
+	e.decision = cal.Evaluate(today)
+	rule := fmt.Sprintf("rule %q", e.decision.Rule)
+
+	switch d := e.decision; {
+	case d.Action == "":
+		return false, fmt.Sprintf("no schedule rule matches %v", today.Format("Monday, 2006-01-02")), crm.ClusterStatus{}, nil
+	case d.Blackout != "" && !d.ShiftedTo.IsZero():
+		return false, fmt.Sprintf("the %v action of %v is blacked out: %v. It is shifted to %v", d.Action, rule, d.Blackout, d.ShiftedTo.Format("Monday, 2006-01-02")), crm.ClusterStatus{}, nil
+	case d.Blackout != "":
+		return false, fmt.Sprintf("the %v action of %v is blacked out: %v. It is skipped", d.Action, rule, d.Blackout), crm.ClusterStatus{}, nil
+	case !d.ShiftedFrom.IsZero():
+		rule += fmt.Sprintf(" (shifted from %v)", d.ShiftedFrom.Format("2006-01-02"))
+	}
+
+	cs, err := e.check(ctx, StepPreCheck)
+	if err != nil {
+		return false, "", cs, err
+	}
+
+	// Failing over only moves the primary role away from the expected primary node, failing back only moves it
+	// back to the expected primary node. Either way nothing is done if the primary role is already where it belongs.
+	switch {
+	case e.decision.Action == schedule.ActionVerify:
+		return false, fmt.Sprintf("%v only verifies the cluster", rule), cs, nil
+	case e.decision.Action == schedule.ActionFailover && e.currentPrimaryNode == e.ExpectedPrimaryNode:
+		return true, fmt.Sprintf("%v moves the primary role away from the expected primary node %v", rule, e.ExpectedPrimaryNode), cs, nil
+	case e.decision.Action == schedule.ActionFailover:
+		return false, fmt.Sprintf("%v fails over but %v is already running as the primary node", rule, e.currentPrimaryNode), cs, nil
+	case e.currentPrimaryNode != e.ExpectedPrimaryNode:
+		return true, fmt.Sprintf("%v fails back: %v is not the expected primary node %v", rule, e.currentPrimaryNode, e.ExpectedPrimaryNode), cs, nil
+	}
+
+	return false, fmt.Sprintf("%v fails back but the expected primary node %v is already the primary node", rule, e.ExpectedPrimaryNode), cs, nil
+}
+
+// failover runs the cluster's failover, the post-failover health check and the probes of the cluster before
+// sending a success notification. If the checks fail and `Rollback` is set the cluster is failed back once.
+func (e *Engine) failover(ctx context.Context) error {
+	if err := e.targetThresholds(e.clusterStatus); err != nil {
+		return e.handleError(ctx, err, e.clusterStatus)
+	}
+
+	originalPrimaryNode := e.currentPrimaryNode
+
+	e.step = StepFailover
+	if err := e.Cluster.Failover(ctx, e.clusterStatus); err != nil {
+		return e.handleError(ctx, err, e.clusterStatus)
+	}
+
+	if cs, err := e.verify(ctx, StepPostCheck, StepProbes); err != nil {
+		if e.Rollback && ctx.Err() == nil {
+			return e.rollback(ctx, err, originalPrimaryNode)
+		}
+		return e.handleError(ctx, err, cs)
+	}
+
+	e.handleSuccess()
+	return nil
+}
+
+// targetThresholds adds the migration thresholds of the nodes the primary role would move to to the report and
+// returns an error if one is critical. A failure during the failover must not ban a resource from the node it is moving to.
+func (e *Engine) targetThresholds(cs crm.ClusterStatus) error {
+	e.report.Merge(e.Policy.Filter(cs, health.MigrationThresholds(cs, e.currentPrimaryNode)))
+	return e.report.Err()
+}
+
+// rollback fails the cluster back to the original primary node with the cluster's own failover, checks its
+// health and runs its probes again, then sends a notification telling whether the cluster was restored.
+// It is only attempted once so a broken cluster does not ping-pong between the nodes.
+func (e *Engine) rollback(ctx context.Context, cause error, originalPrimaryNode string) error {
+	e.step = StepRollback
+	e.probes = nil
+
+	cs, err := e.Status(ctx)
+	if err == nil {
+		err = e.Cluster.Failover(ctx, cs)
+	}
+	if err == nil {
+		cs, err = e.verify(ctx, StepRollback, StepRollback)
+	}
+	if err == nil && e.currentPrimaryNode != originalPrimaryNode {
+		err = fmt.Errorf("the primary node is %v instead of %v", e.currentPrimaryNode, originalPrimaryNode)
+	}
+
+	// The run is aborted instead.
+	if ctx.Err() != nil {
+		return err
+	}
+
+	if err != nil {
+		e.notify(e.RollbackFailedMessage, DefaultRollbackFailedMessage, MessageData{
+			Name:          e.Cluster.Name(),
+			Step:          e.step,
+			Error:         cause,
+			RollbackError: err,
+			PrimaryNode:   e.currentPrimaryNode,
+			ClusterStatus: cs,
+			Report:        e.report,
+			Probes:        e.probes,
+		})
+		return fmt.Errorf("%w: %v; the rollback failed: %v", ErrRollbackFailed, cause, err)
+	}
+
+	e.notify(e.RolledBackMessage, DefaultRolledBackMessage, MessageData{
+		Name:          e.Cluster.Name(),
+		Step:          e.step,
+		Error:         cause,
+		PrimaryNode:   e.currentPrimaryNode,
+		ClusterStatus: cs,
+		Report:        e.report,
+		Probes:        e.probes,
+	})
+	return fmt.Errorf("%w, the cluster was restored on %v: %v", ErrRolledBack, originalPrimaryNode, cause)
+}
+
+// verify checks the health of the cluster and runs its probes after a failover or a rollback.
+func (e *Engine) verify(ctx context.Context, step, probeStep string) (crm.ClusterStatus, error) {
+	cs, err := e.check(ctx, step)
+	if err != nil {
+		return cs, err
+	}
+
+	if p, ok := e.Cluster.(Prober); ok {
+		e.step = probeStep
+		e.probes = p.Probe(ctx)
+		if err := e.probes.Err(); err != nil {
+			return cs, err
+		}
+	}
+
+	return cs, nil
+}
+
+// check pulls the cluster status, checks the health of the cluster and sets the current primary node.
+// An error is returned if any of this fails or the health report contains critical findings.
+func (e *Engine) check(ctx context.Context, step string) (crm.ClusterStatus, error) {
+	e.step = step
+	e.report = health.Report{}
+
+	cs, err := e.Status(ctx)
+	if err != nil {
+		return cs, err
+	}
+
+	report := e.HealthCheck(ctx, cs)
+	if c, ok := e.Cluster.(PreChecker); ok && step == StepPreCheck {
+		report.Merge(c.PreCheck(ctx, cs))
+	}
+
+	e.report = e.Policy.Apply(cs, report)
+	if err := e.report.Err(); err != nil {
+		return cs, err
+	}
+
+	e.clusterStatus = cs
+
+	e.currentPrimaryNode, err = e.Cluster.PrimaryNode(cs)
+	if err != nil {
+		return cs, err
+	}
+
+	return cs, nil
+}
+
+// abort cleans up the cluster, if the failover had already started, and sends the "aborted" notification.
+// The cleanup and the status pulled for the notification are bounded by `CleanupTimeout`.
+func (e *Engine) abort(cause error) error {
+	timeout := e.CleanupTimeout
+	if timeout <= 0 {
+		timeout = DefaultCleanupTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reason := fmt.Sprintf("the run was interrupted (%v)", cause)
+
+	if c, ok := e.Cluster.(Cleaner); ok && (e.step == StepFailover || e.step == StepPostCheck || e.step == StepProbes || e.step == StepRollback) {
+		if err := c.Cleanup(ctx); err != nil {
+			reason += fmt.Sprintf("\n\nThe cleanup of the cluster also failed:\n%v", err)
+		} else {
+			reason += "\n\nThe cleanup of the cluster completed."
+		}
+	}
+
+	cs := e.clusterStatus
+	if status, err := e.Status(ctx); err == nil {
+		cs = status
+	}
+
+	err := fmt.Errorf("%w while running the %v step: %v", ErrAborted, e.step, cause)
+
+	e.notify(e.AbortedMessage, DefaultAbortedMessage, MessageData{
+		Name:          e.Cluster.Name(),
+		Step:          e.step,
+		Error:         errors.New(reason),
+		PrimaryNode:   e.currentPrimaryNode,
+		ClusterStatus: cs,
+		Report:        e.report,
+		Probes:        e.probes,
+	})
+
+	return err
+}
+
+// MessageData contains the fields available to the notification templates.
+type MessageData struct {
+	Name  string
+	Step  string
+	Error error
+	// RollbackError is the reason the rollback failed.
+	RollbackError error
+	PrimaryNode   string
+	ClusterStatus crm.ClusterStatus
+	// Report is the report of the last health check.
+	Report health.Report
+	// Probes are the results of the probes run after the failover, if any.
+	Probes probe.Results
+	// Reason is the reason a scheduled action was skipped.
+	Reason string
+}
+
+// DefaultErrorMessage is the notification sent when a failover fails.
+const DefaultErrorMessage = `There was an error encounter when attempting to perform a failover on the {{.Name}} nodes.
+Failover procedures will not be performed until this is corrected. Please see the error message below along with the cluster status.
+
+Error Message:
+{{.Error}}
+
+Health Report:
+{{.Report}}
+{{if .Probes}}Probe Results:
+{{.Probes}}
+{{end}}Cluster Status:
+{{.ClusterStatus}}
+`
+
+// DefaultSuccessMessage is the notification sent upon successful failover.
+const DefaultSuccessMessage = `
+Failover procedure completed without detected errors.
+Please see the output below to verify that the cluster looks healthy.
+
+Current Primary Node: {{.PrimaryNode}}
+
+Health Report:
+{{.Report}}
+{{if .Probes}}Probe Results:
+{{.Probes}}
+{{end}}Cluster Status: 
+{{.ClusterStatus}}
+`
+
+// DefaultAbortedMessage is the notification sent when a run is interrupted.
+const DefaultAbortedMessage = `The failover of the {{.Name}} nodes was aborted while the {{.Step}} step was in progress.
+The cluster may not be in the expected state. Please verify the cluster status below and correct it manually if needed.
+
+Reason:
+{{.Error}}
+
+Health Report:
+{{.Report}}
+Cluster Status:
+{{.ClusterStatus}}
+`
+
+// DefaultRolledBackMessage is the notification sent when a failed failover was rolled back.
+const DefaultRolledBackMessage = `A failover of the {{.Name}} nodes was attempted but the cluster was not healthy afterwards.
+The failover was rolled back and the cluster was restored on {{.PrimaryNode}}. Please investigate the error below before the next failover.
+
+Error Message:
+{{.Error}}
+
+Health Report:
+{{.Report}}
+{{if .Probes}}Probe Results:
+{{.Probes}}
+{{end}}Cluster Status:
+{{.ClusterStatus}}
+`
+
+// DefaultRollbackFailedMessage is the notification sent when a failed failover could not be rolled back.
+const DefaultRollbackFailedMessage = `A failover of the {{.Name}} nodes was attempted but the cluster was not healthy afterwards.
+The rollback to the original primary node ALSO FAILED. The cluster needs to be fixed manually.
+
+Error Message:
+{{.Error}}
+
+Rollback Error:
+{{.RollbackError}}
+
+Health Report:
+{{.Report}}
+{{if .Probes}}Probe Results:
+{{.Probes}}
+{{end}}Cluster Status:
+{{.ClusterStatus}}
+`
+
+// DefaultSkippedMessage is the notification sent when a scheduled action falls in a blackout.
+const DefaultSkippedMessage = `The scheduled failover of the {{.Name}} nodes was not performed.
+
+Reason:
+{{.Reason}}
+`
+
+// handleError sends a notification containing the error and the cluster status. The error is returned as is.
+// No notification is sent if the context is done, the "aborted" notification is sent instead.
+func (e *Engine) handleError(ctx context.Context, err error, cs crm.ClusterStatus) error {
+	if ctx.Err() != nil {
+		return err
+	}
+
+	e.notify(e.ErrorMessage, DefaultErrorMessage, MessageData{
+		Name:          e.Cluster.Name(),
+		Step:          e.step,
+		Error:         err,
+		PrimaryNode:   e.currentPrimaryNode,
+		ClusterStatus: cs,
+		Report:        e.report,
+		Probes:        e.probes,
+	})
+	return err
+}
+
+// handleSuccess sends a notification upon successful failover.
+func (e *Engine) handleSuccess() {
+	e.notify(e.SuccessMessage, DefaultSuccessMessage, MessageData{
+		Name:          e.Cluster.Name(),
+		Step:          e.step,
+		PrimaryNode:   e.currentPrimaryNode,
+		ClusterStatus: e.clusterStatus,
+		Report:        e.report,
+		Probes:        e.probes,
+	})
+}
+
+// handleSkipped sends a notification when a scheduled action is skipped because of a blackout.
+func (e *Engine) handleSkipped(reason string) {
+	e.notify(e.SkippedMessage, DefaultSkippedMessage, MessageData{
+		Name:   e.Cluster.Name(),
+		Reason: reason,
+	})
+}
+
+// notify renders the message template, falling back to the default template if it is empty
+// or invalid, and sends the result.
+func (e *Engine) notify(text, defaultText string, data MessageData) {
+	if text == "" {
+		text = defaultText
+	}
+
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		tmpl = template.Must(template.New("message").Parse(defaultText))
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		buf.Reset()
+		template.Must(template.New("message").Parse(defaultText)).Execute(&buf, data)
+	}
+
+	e.Notify(buf.String())
+}
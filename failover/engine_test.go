@@ -0,0 +1,359 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Dates of the default schedule: the 1st Sunday of the month fails over, every other Sunday fails back.
+var (
+	firstSunday  = time.Date(2026, time.November, 1, 3, 0, 0, 0, time.UTC)
+	secondSunday = time.Date(2026, time.November, 8, 3, 0, 0, 0, time.UTC)
+	monday       = time.Date(2026, time.November, 9, 3, 0, 0, 0, time.UTC)
+)
+
+// fakeCluster is the state of a two node cluster running the `dwgrp` group. `pcs resource move dwgrp`
+// moves the group to the other node.
+type fakeCluster struct {
+	mu sync.Mutex
+	// primary is the node running the group.
+	primary string
+	// offline is a node that is offline.
+	offline string
+	// broken lists, for each move, whether the `app` resource is failed afterwards.
+	broken []bool
+	moves  int
+}
+
+func (c *fakeCluster) move() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.primary == "node1" {
+		c.primary = "node2"
+	} else {
+		c.primary = "node1"
+	}
+	c.moves++
+}
+
+func (c *fakeCluster) status(ctx context.Context) (crm.ClusterStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	failed := c.moves > 0 && c.moves <= len(c.broken) && c.broken[c.moves-1]
+
+	var nodes string
+	for _, n := range []string{"node1", "node2"} {
+		nodes += fmt.Sprintf(`<node name="%v" online="%v" standby="false" maintenance="false" pending="false" unclean="false" shutdown="false" is_dc="%v" type="member"/>`,
+			n, n != c.offline, n == "node1")
+	}
+
+	doc := fmt.Sprintf(`<crm_mon version="1.1.23">
+  <summary>
+    <stack type="corosync"/>
+    <current_dc name="node1" with_quorum="true"/>
+    <nodes_configured number="2"/>
+    <resources_configured number="2" disabled="0" blocked="0"/>
+    <cluster_options stonith-enabled="true" symmetric-cluster="true" no-quorum-policy="stop" maintenance-mode="false"/>
+  </summary>
+  <nodes>%v</nodes>
+  <resources>
+    <group id="dwgrp" number_resources="2">
+      <resource id="vip" resource_agent="ocf::heartbeat:IPaddr2" role="Started" active="true" orphaned="false" blocked="false" managed="true" failed="false" failure_ignored="false" nodes_running_on="1">
+        <node name="%[2]v" id="1" cached="false"/>
+      </resource>
+      <resource id="app" resource_agent="systemd:dw" role="Started" active="true" orphaned="false" blocked="false" managed="true" failed="%[3]v" failure_ignored="false" nodes_running_on="1">
+        <node name="%[2]v" id="1" cached="false"/>
+      </resource>
+    </group>
+  </resources>
+</crm_mon>`, nodes, c.primary, failed)
+
+	cs, err := crm.ParseStatus(strings.NewReader(doc))
+	if err != nil {
+		return crm.ClusterStatus{}, err
+	}
+	return *cs, nil
+}
+
+// newTestEngine returns an engine failing over the fake cluster with a `runner.Fake`. `onMove` is called
+// when the failover command runs, the group is moved unless it returns an error.
+func newTestEngine(c *fakeCluster, now time.Time, onMove func(ctx context.Context) error) (*Engine, *runner.Fake, *[]string) {
+	fake := &runner.Fake{
+		Results: map[string]runner.Result{
+			"pcs resource clear dwgrp": {},
+		},
+		Handler: func(ctx context.Context, cmd runner.Command) (runner.Result, error) {
+			res := runner.Result{Command: cmd}
+			if cmd.String() != "pcs resource move dwgrp" {
+				res.ExitCode = 127
+				return res, &runner.Error{Result: res, Err: errors.New("executable file not found")}
+			}
+			if onMove != nil {
+				if err := onMove(ctx); err != nil {
+					res.ExitCode = 1
+					return res, &runner.Error{Result: res, Err: err}
+				}
+			}
+			c.move()
+			return res, nil
+		},
+	}
+
+	profile := Profile{
+		Primary:  PrimaryRule{Group: "dwgrp"},
+		Failover: []runner.Command{runner.NewCommand("pcs", "resource", "move", "dwgrp")},
+		Cleanup:  []runner.Command{runner.NewCommand("pcs", "resource", "clear", "dwgrp")},
+		Converge: Convergence{Interval: time.Millisecond, Timeout: time.Second, SkipDCCheck: true},
+		Rollback: true,
+	}
+
+	var notifications []string
+	engine := &Engine{
+		Cluster:             &ProfileCluster{Profile: profile, Executor: fake, Status: c.status},
+		ExpectedPrimaryNode: "node1",
+		Now:                 func() time.Time { return now },
+		Rollback:            profile.Rollback,
+		CleanupTimeout:      time.Second,
+		Status:              c.status,
+		HealthCheck: func(ctx context.Context, cs crm.ClusterStatus) health.Report {
+			return health.Evaluate(cs)
+		},
+		Notify: func(msg string) {
+			notifications = append(notifications, msg)
+		},
+	}
+
+	return engine, fake, &notifications
+}
+
+// commands returns the command lines run by the fake executor.
+func commands(fake *runner.Fake) []string {
+	var cmds []string
+	for _, cmd := range fake.Calls() {
+		cmds = append(cmds, cmd.String())
+	}
+	return cmds
+}
+
+func TestEngineRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster *fakeCluster
+		now     time.Time
+		// noRollback disables the rollback of a failover failing its post-checks.
+		noRollback bool
+		want       Outcome
+		exitCode   int
+		wantErr    error
+		// primary is the node running the group after the run.
+		primary  string
+		commands []string
+		// notification is a substring of the only notification sent, no notification is expected if empty.
+		notification string
+	}{
+		{
+			name:     "skipped on a day without a rule",
+			cluster:  &fakeCluster{primary: "node1"},
+			now:      monday,
+			want:     OutcomeSkipped,
+			exitCode: 2,
+			primary:  "node1",
+		},
+		{
+			name:     "skipped when the expected primary node is already the primary",
+			cluster:  &fakeCluster{primary: "node1"},
+			now:      secondSunday,
+			want:     OutcomeSkipped,
+			exitCode: 2,
+			primary:  "node1",
+		},
+		{
+			name:         "fails over on the 1st Sunday",
+			cluster:      &fakeCluster{primary: "node1"},
+			now:          firstSunday,
+			want:         OutcomeSuccess,
+			exitCode:     0,
+			primary:      "node2",
+			commands:     []string{"pcs resource move dwgrp", "pcs resource clear dwgrp"},
+			notification: "Current Primary Node: node2",
+		},
+		{
+			name:         "fails back on the 2nd Sunday",
+			cluster:      &fakeCluster{primary: "node2"},
+			now:          secondSunday,
+			want:         OutcomeSuccess,
+			exitCode:     0,
+			primary:      "node1",
+			commands:     []string{"pcs resource move dwgrp", "pcs resource clear dwgrp"},
+			notification: "Current Primary Node: node1",
+		},
+		{
+			name:         "pre-check failure",
+			cluster:      &fakeCluster{primary: "node1", offline: "node2"},
+			now:          firstSunday,
+			want:         OutcomePreCheckFailed,
+			exitCode:     3,
+			wantErr:      ErrUnhealthyNode,
+			primary:      "node1",
+			notification: "node node2 is offline",
+		},
+		{
+			name:         "post-check failure",
+			cluster:      &fakeCluster{primary: "node1", broken: []bool{true}},
+			now:          firstSunday,
+			noRollback:   true,
+			want:         OutcomePostCheckFailed,
+			exitCode:     5,
+			wantErr:      ErrUnhealthyResource,
+			primary:      "node2",
+			commands:     []string{"pcs resource move dwgrp", "pcs resource clear dwgrp"},
+			notification: "resource app has failed on node2",
+		},
+		{
+			name:         "post-check failure rolled back",
+			cluster:      &fakeCluster{primary: "node1", broken: []bool{true, false}},
+			now:          firstSunday,
+			want:         OutcomeRolledBack,
+			exitCode:     7,
+			wantErr:      ErrRolledBack,
+			primary:      "node1",
+			commands:     []string{"pcs resource move dwgrp", "pcs resource clear dwgrp", "pcs resource move dwgrp", "pcs resource clear dwgrp"},
+			notification: "the cluster was restored on node1",
+		},
+		{
+			name:         "post-check failure with a failed rollback",
+			cluster:      &fakeCluster{primary: "node1", broken: []bool{true, true}},
+			now:          firstSunday,
+			want:         OutcomeRollbackFailed,
+			exitCode:     8,
+			wantErr:      ErrRollbackFailed,
+			primary:      "node1",
+			commands:     []string{"pcs resource move dwgrp", "pcs resource clear dwgrp", "pcs resource move dwgrp", "pcs resource clear dwgrp"},
+			notification: "The rollback to the original primary node ALSO FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, fake, notifications := newTestEngine(tt.cluster, tt.now, nil)
+			engine.Rollback = !tt.noRollback
+
+			got, err := engine.Run(context.Background())
+			if got != tt.want {
+				t.Errorf("Run() outcome = %v, want %v (error: %v)", got, tt.want, err)
+			}
+			if got.ExitCode() != tt.exitCode {
+				t.Errorf("Run() exit code = %v, want %v", got.ExitCode(), tt.exitCode)
+			}
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Run() error = %v, want nil", err)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.cluster.primary != tt.primary {
+				t.Errorf("primary node after Run() = %v, want %v", tt.cluster.primary, tt.primary)
+			}
+
+			if got, want := strings.Join(commands(fake), "\n"), strings.Join(tt.commands, "\n"); got != want {
+				t.Errorf("Run() ran:\n%v\nwant:\n%v", got, want)
+			}
+
+			switch {
+			case tt.notification == "" && len(*notifications) > 0:
+				t.Errorf("Run() sent %v notification(s), want none", len(*notifications))
+			case tt.notification != "" && len(*notifications) != 1:
+				t.Errorf("Run() sent %v notification(s), want 1", len(*notifications))
+			case tt.notification != "" && !strings.Contains((*notifications)[0], tt.notification):
+				t.Errorf("notification does not contain %q:\n%v", tt.notification, (*notifications)[0])
+			}
+		})
+	}
+}
+
+func TestEngineRunFailoverFailed(t *testing.T) {
+	cluster := &fakeCluster{primary: "node1"}
+	engine, fake, notifications := newTestEngine(cluster, firstSunday, func(ctx context.Context) error {
+		return errors.New("Error: resource 'dwgrp' is not running on any node")
+	})
+
+	got, err := engine.Run(context.Background())
+	if got != OutcomeFailoverFailed || got.ExitCode() != 4 {
+		t.Errorf("Run() outcome = %v (exit code %v), want %v (exit code 4)", got, got.ExitCode(), OutcomeFailoverFailed)
+	}
+	if !errors.Is(err, ErrCommandFailed) {
+		t.Errorf("Run() error = %v, want %v", err, ErrCommandFailed)
+	}
+	if got := commands(fake); len(got) != 1 {
+		t.Errorf("Run() ran %v, want only the failover command", got)
+	}
+	if len(*notifications) != 1 || !strings.Contains((*notifications)[0], "There was an error") {
+		t.Errorf("Run() sent %q, want the error notification", *notifications)
+	}
+}
+
+func TestEngineRunAborted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The run is interrupted while the failover command is running.
+	cluster := &fakeCluster{primary: "node1"}
+	engine, fake, notifications := newTestEngine(cluster, firstSunday, func(context.Context) error {
+		cancel()
+		return context.Canceled
+	})
+
+	got, err := engine.Run(ctx)
+	if got != OutcomeAborted || got.ExitCode() != 6 {
+		t.Errorf("Run() outcome = %v (exit code %v), want %v (exit code 6)", got, got.ExitCode(), OutcomeAborted)
+	}
+	if !errors.Is(err, ErrAborted) {
+		t.Errorf("Run() error = %v, want %v", err, ErrAborted)
+	}
+
+	want := []string{"pcs resource move dwgrp", "pcs resource clear dwgrp"}
+	if got := commands(fake); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Run() ran %v, want %v", got, want)
+	}
+
+	if len(*notifications) != 1 {
+		t.Fatalf("Run() sent %v notification(s), want 1", len(*notifications))
+	}
+	for _, s := range []string{"aborted while the failover step", "The cleanup of the cluster completed."} {
+		if !strings.Contains((*notifications)[0], s) {
+			t.Errorf("notification does not contain %q:\n%v", s, (*notifications)[0])
+		}
+	}
+}
+
+func TestEngineRunNotLeader(t *testing.T) {
+	cluster := &fakeCluster{primary: "node1"}
+	engine, fake, notifications := newTestEngine(cluster, firstSunday, nil)
+	engine.Lock = func(ctx context.Context) (func(), error) {
+		return nil, fmt.Errorf("%w: the lock is held by node2", ErrNotLeader)
+	}
+
+	got, err := engine.Run(context.Background())
+	if got != OutcomeNotLeader || got.ExitCode() != 9 {
+		t.Errorf("Run() outcome = %v (exit code %v), want %v (exit code 9)", got, got.ExitCode(), OutcomeNotLeader)
+	}
+	if !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Run() error = %v, want %v", err, ErrNotLeader)
+	}
+	if len(fake.Calls()) > 0 || len(*notifications) > 0 {
+		t.Errorf("Run() ran %v and sent %v notification(s), want nothing", commands(fake), len(*notifications))
+	}
+}
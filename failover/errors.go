@@ -0,0 +1,38 @@
+package failover
+
+import (
+	"errors"
+
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/lock"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Errors returned by the engine and the health checks. They are usually wrapped with more
+// details and should be tested for with `errors.Is`.
+var (
+	// ErrUnhealthyCluster is returned when the cluster has no quorum, STONITH is disabled, it is in maintenance mode, etc.
+	ErrUnhealthyCluster = health.ErrUnhealthyCluster
+	// ErrUnhealthyNode is returned when a node is not online or is in standby, maintenance, etc.
+	ErrUnhealthyNode = health.ErrUnhealthyNode
+	// ErrUnhealthyResource is returned when a resource is not in a healthy state.
+	ErrUnhealthyResource = health.ErrUnhealthyResource
+	// ErrMigrationThreshold is returned when a resource reached, or is about to reach, its migration threshold on a node.
+	ErrMigrationThreshold = health.ErrMigrationThreshold
+	// ErrPrimaryNotFound is returned when the primary node of the cluster cannot be found.
+	ErrPrimaryNotFound = errors.New("unable to find primary node in cluster. please check the cluster's health")
+	// ErrCommandFailed is returned when an external command could not be run or exited with a non-zero exit code.
+	ErrCommandFailed = runner.ErrCommandFailed
+	// ErrInvalidProfile is returned when a profile is missing required settings.
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrNotConverged is returned when the cluster did not converge before the deadline.
+	ErrNotConverged = errors.New("cluster did not converge")
+	// ErrRolledBack is returned when the failover failed its post-failover checks and was rolled back.
+	ErrRolledBack = errors.New("failover failed and was rolled back")
+	// ErrRollbackFailed is returned when the failover failed its post-failover checks and could not be rolled back.
+	ErrRollbackFailed = errors.New("failover failed and the rollback also failed")
+	// ErrAborted is returned when a run is cancelled, e.g. by SIGINT, before it finished.
+	ErrAborted = errors.New("failover aborted")
+	// ErrNotLeader is returned when another node, or another run on this node, holds the run lock.
+	ErrNotLeader = lock.ErrNotLeader
+)
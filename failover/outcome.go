@@ -0,0 +1,62 @@
+package failover
+
+// Outcome is the result of a run. Each outcome maps to a distinct exit code so monitoring can tell
+// them apart.
+//
+//	0  Success            the failover was performed
+//	1  Error              invalid configuration or usage, nothing was attempted
+//	2  Skipped            nothing to do today or the primary is already correct
+//	3  Pre-check failed   the cluster was unhealthy, no failover was attempted
+//	4  Failover failed    a failover command failed or the cluster did not converge
+//	5  Post-check failed  the cluster is unhealthy or a probe failed after the failover
+//	6  Aborted            the run was interrupted
+//	7  Rolled back        the failover failed its post-checks and the cluster was restored on the original primary
+//	8  Rollback failed    the failover failed its post-checks and could not be rolled back
+//	9  Not leader         another node, or another run on this node, holds the run lock
+type Outcome int
+
+// Outcomes of a run.
+const (
+	OutcomeSuccess Outcome = iota
+	OutcomeError
+	OutcomeSkipped
+	OutcomePreCheckFailed
+	OutcomeFailoverFailed
+	OutcomePostCheckFailed
+	OutcomeAborted
+	OutcomeRolledBack
+	OutcomeRollbackFailed
+	OutcomeNotLeader
+)
+
+// ExitCode returns the process exit code of the outcome.
+func (o Outcome) ExitCode() int {
+	return int(o)
+}
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeError:
+		return "error"
+	case OutcomeSkipped:
+		return "skipped"
+	case OutcomePreCheckFailed:
+		return "pre-check failed"
+	case OutcomeFailoverFailed:
+		return "failover failed"
+	case OutcomePostCheckFailed:
+		return "post-check failed"
+	case OutcomeAborted:
+		return "aborted"
+	case OutcomeRolledBack:
+		return "rolled back"
+	case OutcomeRollbackFailed:
+		return "rollback failed"
+	case OutcomeNotLeader:
+		return "not leader"
+	}
+
+	return "unknown"
+}
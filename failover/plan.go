@@ -0,0 +1,107 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+)
+
+// Planner is implemented by clusters that can describe the steps of their failover without running them.
+type Planner interface {
+	Plan(cs crm.ClusterStatus) []PlannedStep
+}
+
+// PlannedStep is a step a failover would perform. Steps without a command, like waiting for the cluster
+// to converge, only have a reason.
+type PlannedStep struct {
+	Command string `json:"command,omitempty"`
+	Reason  string `json:"reason"`
+	// Mutating is set on the commands changing the state of the cluster.
+	Mutating bool `json:"mutating"`
+}
+
+// Plan describes what a run would do, see `Engine.Plan`.
+type Plan struct {
+	Name                string `json:"name"`
+	Failover            bool   `json:"failover"`
+	Reason              string `json:"reason"`
+	CurrentPrimaryNode  string `json:"currentPrimaryNode,omitempty"`
+	ExpectedPrimaryNode string `json:"expectedPrimaryNode"`
+	// Outcome is the outcome the run would most likely have.
+	Outcome string        `json:"outcome"`
+	Error   string        `json:"error,omitempty"`
+	Report  health.Report `json:"report"`
+	Steps   []PlannedStep `json:"steps,omitempty"`
+}
+
+func (p Plan) String() string {
+	str := fmt.Sprintf("Dry run of the %v failover, nothing was changed.\n\n", p.Name)
+	str += fmt.Sprintf("  Current Primary Node : %v\n", p.CurrentPrimaryNode)
+	str += fmt.Sprintf("  Expected Primary Node: %v\n", p.ExpectedPrimaryNode)
+	str += fmt.Sprintf("  Outcome              : %v\n", p.Outcome)
+
+	switch {
+	case p.Error != "":
+		str += fmt.Sprintf("  Decision             : no failover, %v\n", p.Error)
+	case p.Failover:
+		str += fmt.Sprintf("  Decision             : failover, %v\n", p.Reason)
+	default:
+		str += fmt.Sprintf("  Decision             : no failover, %v\n", p.Reason)
+	}
+
+	str += "\nHealth Report:\n" + p.Report.String()
+
+	if len(p.Steps) > 0 {
+		str += "\nSteps:\n"
+	}
+
+	for i, s := range p.Steps {
+		kind := "read-only"
+		if s.Mutating {
+			kind = "mutating"
+		}
+
+		if s.Command != "" {
+			str += fmt.Sprintf("  %2d. [%v] %v\n      %v\n", i+1, kind, s.Command, s.Reason)
+		} else {
+			str += fmt.Sprintf("  %2d. %v\n", i+1, s.Reason)
+		}
+	}
+
+	return str
+}
+
+// Plan evaluates the schedule and runs the read-only pre-failover checks like `Run` does, but instead of
+// performing the failover it returns the steps the failover would perform. No notification is sent.
+// The outcome is the one a run would most likely have, an error is returned if the checks fail.
+func (e *Engine) Plan(ctx context.Context) (Plan, Outcome, error) {
+	e.step = ""
+	e.report = health.Report{}
+
+	plan := Plan{Name: e.Cluster.Name(), ExpectedPrimaryNode: e.ExpectedPrimaryNode}
+
+	perform, reason, cs, err := e.decide(ctx)
+	if err == nil && perform {
+		err = e.targetThresholds(cs)
+	}
+
+	plan.Failover, plan.Reason, plan.Report = perform && err == nil, reason, e.report
+	plan.CurrentPrimaryNode = e.currentPrimaryNode
+
+	outcome := OutcomeSkipped
+	switch {
+	case err != nil:
+		outcome = OutcomePreCheckFailed
+		plan.Error = err.Error()
+	case plan.Failover:
+		outcome = OutcomeSuccess
+		if p, ok := e.Cluster.(Planner); ok {
+			plan.Steps = p.Plan(cs)
+		}
+	}
+	plan.Outcome = outcome.String()
+
+	return plan, outcome, err
+}
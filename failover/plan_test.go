@@ -0,0 +1,108 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnginePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster *fakeCluster
+		now     time.Time
+		want    Outcome
+		wantErr error
+		// primary is the current primary node of the plan, it is only known once the cluster passed the checks.
+		primary string
+		// steps are the commands of the planned steps.
+		steps []string
+	}{
+		{
+			name:    "skipped on a day without a rule",
+			cluster: &fakeCluster{primary: "node1"},
+			now:     monday,
+			want:    OutcomeSkipped,
+		},
+		{
+			name:    "fails over on the 1st Sunday",
+			cluster: &fakeCluster{primary: "node1"},
+			now:     firstSunday,
+			want:    OutcomeSuccess,
+			primary: "node1",
+			steps:   []string{"pcs resource move dwgrp", "pcs resource clear dwgrp"},
+		},
+		{
+			name:    "pre-check failure",
+			cluster: &fakeCluster{primary: "node1", offline: "node2"},
+			now:     firstSunday,
+			want:    OutcomePreCheckFailed,
+			wantErr: ErrUnhealthyNode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, fake, notifications := newTestEngine(tt.cluster, tt.now, nil)
+
+			plan, got, err := engine.Plan(context.Background())
+			if got != tt.want || plan.Outcome != tt.want.String() {
+				t.Errorf("Plan() outcome = %v (plan %v), want %v (error: %v)", got, plan.Outcome, tt.want, err)
+			}
+
+			switch {
+			case tt.wantErr == nil && (err != nil || plan.Error != ""):
+				t.Errorf("Plan() error = %v (plan %q), want nil", err, plan.Error)
+			case tt.wantErr != nil && (!errors.Is(err, tt.wantErr) || plan.Error != err.Error()):
+				t.Errorf("Plan() error = %v (plan %q), want %v", err, plan.Error, tt.wantErr)
+			}
+
+			if plan.Failover != (tt.want == OutcomeSuccess) {
+				t.Errorf("Plan() failover = %v, want %v", plan.Failover, tt.want == OutcomeSuccess)
+			}
+			if plan.CurrentPrimaryNode != tt.primary || plan.ExpectedPrimaryNode != "node1" {
+				t.Errorf("Plan() primary nodes = %q, %q, want %q, node1", plan.CurrentPrimaryNode, plan.ExpectedPrimaryNode, tt.primary)
+			}
+
+			var steps []string
+			for _, s := range plan.Steps {
+				if s.Command != "" {
+					steps = append(steps, s.Command)
+				}
+			}
+			if !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("Plan() steps = %v, want %v", steps, tt.steps)
+			}
+
+			// Nothing is changed and nobody is notified.
+			if len(fake.Calls()) > 0 || len(*notifications) > 0 || tt.cluster.moves > 0 {
+				t.Errorf("Plan() ran %v and sent %v notification(s), want nothing", commands(fake), len(*notifications))
+			}
+		})
+	}
+}
+
+func TestPlanString(t *testing.T) {
+	engine, _, _ := newTestEngine(&fakeCluster{primary: "node1"}, firstSunday, nil)
+
+	plan, _, err := engine.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	str := plan.String()
+	for _, s := range []string{
+		"Current Primary Node : node1",
+		"Decision             : failover",
+		"[mutating] pcs resource move dwgrp",
+		"[mutating] pcs resource clear dwgrp",
+		"No problems found.",
+	} {
+		if !strings.Contains(str, s) {
+			t.Errorf("Plan.String() does not contain %q:\n%v", s, str)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Codes of the findings of the PostgreSQL checks.
+const (
+	CodePostgresDataStatus  = "pg-data-status"
+	CodePostgresMasterScore = "pg-master-score"
+	CodePostgresNoStandby   = "pg-no-standby"
+	CodePostgresReplication = "pg-replication"
+	CodePostgresLag         = "pg-lag"
+)
+
+// DefaultReplicationQuery returns the replication state of each standby from `pg_stat_replication`
+// on PostgreSQL 10 and later. Custom queries must return the same columns: the name, state and
+// sync state of the standby, its replay lag in bytes and its replay lag in seconds.
+const DefaultReplicationQuery = `SELECT application_name, state, sync_state,
+	COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn), 0),
+	COALESCE(EXTRACT(EPOCH FROM replay_lag), 0)
+FROM pg_stat_replication`
+
+// PostgresCheck checks that a PostgreSQL standby managed by the `pgsql` resource agent is ready to take
+// over before a switchover. The node attributes of the agent are always checked, `pg_stat_replication`
+// is only queried if a connection is configured.
+//
+// Example config:
+//
+//	postgres:
+//	  resource: pgsql
+//	  maxLagBytes: 16777216
+//	  maxLagTime: 30s
+//	  replication:
+//	    connection: "host=pkmdb-vip user=postgres dbname=postgres"
+type PostgresCheck struct {
+	// Resource is the id of the pgsql resource. The attributes checked are named after it:
+	// `<resource>-data-status`, `<resource>-xlog-loc` and `master-<resource>`. The check is disabled if it is empty.
+	Resource string `mapstructure:"resource"`
+	// MaxLagBytes and MaxLagTime are the largest replication lags accepted. Zero disables the limit.
+	MaxLagBytes int64         `mapstructure:"maxLagBytes"`
+	MaxLagTime  time.Duration `mapstructure:"maxLagTime"`
+	Replication Replication   `mapstructure:"replication"`
+}
+
+// Replication describes how `pg_stat_replication` is queried on the primary.
+type Replication struct {
+	// Connection is the libpq connection string passed to `psql`. The query is skipped if it is empty.
+	Connection string `mapstructure:"connection"`
+	// Command is the `psql` command, `psql` by default. Use it to run psql as another user, e.g. `sudo -u postgres psql`.
+	Command runner.Command `mapstructure:"command"`
+	// Query replaces `DefaultReplicationQuery`, e.g. for PostgreSQL 9.x.
+	Query string `mapstructure:"query"`
+}
+
+// Enabled returns true if the check is configured.
+func (c PostgresCheck) Enabled() bool {
+	return c.Resource != ""
+}
+
+// Check returns the findings of the PostgreSQL checks. Every online node other than the primary is
+// considered a standby. A standby must be `STREAMING|SYNC` with a positive master score and its lag
+// must be below the limits.
+func (c PostgresCheck) Check(ctx context.Context, executor runner.Executor, cs crm.ClusterStatus, primary string) health.Report {
+	var r health.Report
+
+	dataStatus := c.Resource + "-data-status"
+	xlogLoc := c.Resource + "-xlog-loc"
+	masterScore := "master-" + c.Resource
+
+	if status, _ := cs.NodeAttribute(primary, dataStatus); status != "LATEST" {
+		r.Add(health.Critical, CodePostgresDataStatus, primary, c.Resource, "the data of the primary %v is %v, expected LATEST", primary, orUnknown(status))
+	}
+
+	var standbys []string
+	for _, n := range cs.Nodes {
+		if n.Online && n.Name != primary {
+			standbys = append(standbys, n.Name)
+		}
+	}
+
+	if len(standbys) == 0 {
+		r.Add(health.Critical, CodePostgresNoStandby, "", c.Resource, "there is no standby to switch over to")
+	}
+
+	for _, node := range standbys {
+		status, _ := cs.NodeAttribute(node, dataStatus)
+		if status != "STREAMING|SYNC" {
+			r.Add(health.Critical, CodePostgresDataStatus, node, c.Resource, "the standby %v is %v, expected STREAMING|SYNC", node, orUnknown(status))
+		}
+
+		value, _ := cs.NodeAttribute(node, masterScore)
+		if score, err := crm.ParseScore(value); value == "" || err != nil || score <= 0 {
+			r.Add(health.Critical, CodePostgresMasterScore, node, c.Resource, "the standby %v cannot be promoted, its %v score is %v", node, masterScore, orUnknown(value))
+		}
+
+		// The agent only records the xlog locations while electing a primary, they are usually missing.
+		if lag, ok := xlogLag(cs, primary, node, xlogLoc); ok && c.MaxLagBytes > 0 && lag > c.MaxLagBytes {
+			r.Add(health.Critical, CodePostgresLag, node, c.Resource, "the standby %v is %v bytes behind the primary according to %v, the limit is %v", node, lag, xlogLoc, c.MaxLagBytes)
+		}
+
+		if status == "STREAMING|SYNC" {
+			r.Add(health.Info, CodePostgresDataStatus, node, c.Resource, "the standby %v is %v with a %v score of %v", node, status, masterScore, value)
+		}
+	}
+
+	if c.Replication.Connection != "" {
+		r.Merge(c.checkReplication(ctx, executor))
+	}
+
+	return r
+}
+
+// checkReplication queries `pg_stat_replication` on the primary.
+func (c PostgresCheck) checkReplication(ctx context.Context, executor runner.Executor) health.Report {
+	var r health.Report
+
+	cmd := c.Replication.Command
+	if len(cmd.Args) == 0 {
+		cmd = runner.NewCommand("psql")
+	}
+
+	query := c.Replication.Query
+	if query == "" {
+		query = DefaultReplicationQuery
+	}
+
+	cmd.Args = append(append([]string{}, cmd.Args...), "-X", "-A", "-t", "-q", "-F", "|", "-d", c.Replication.Connection, "-c", query)
+
+	res, err := executor.Run(ctx, cmd)
+	if err != nil {
+		r.Add(health.Critical, CodePostgresReplication, "", c.Resource, "unable to query pg_stat_replication: %v", err)
+		return r
+	}
+
+	var standbys int
+	for _, line := range strings.Split(strings.TrimSpace(res.Stdout), "\n") {
+		if line == "" {
+			continue
+		}
+		standbys++
+
+		fields := strings.Split(line, "|")
+		if len(fields) != 5 {
+			r.Add(health.Critical, CodePostgresReplication, "", c.Resource, "unexpected pg_stat_replication row %q, expected 5 columns", line)
+			continue
+		}
+
+		name, state, syncState := fields[0], fields[1], fields[2]
+		lagBytes, _ := strconv.ParseFloat(fields[3], 64)
+		lagSeconds, _ := strconv.ParseFloat(fields[4], 64)
+		lagTime := time.Duration(lagSeconds * float64(time.Second))
+
+		switch {
+		case state != "streaming" || syncState != "sync":
+			r.Add(health.Critical, CodePostgresReplication, "", c.Resource, "the standby %v is %v (%v), expected streaming (sync)", name, state, syncState)
+		case c.MaxLagBytes > 0 && int64(lagBytes) > c.MaxLagBytes:
+			r.Add(health.Critical, CodePostgresLag, "", c.Resource, "the standby %v is %v bytes behind the primary, the limit is %v", name, int64(lagBytes), c.MaxLagBytes)
+		case c.MaxLagTime > 0 && lagTime > c.MaxLagTime:
+			r.Add(health.Critical, CodePostgresLag, "", c.Resource, "the standby %v is %v behind the primary, the limit is %v", name, lagTime, c.MaxLagTime)
+		default:
+			r.Add(health.Info, CodePostgresReplication, "", c.Resource, "the standby %v is %v (%v), %v bytes and %v behind the primary", name, state, syncState, int64(lagBytes), lagTime)
+		}
+	}
+
+	if standbys == 0 {
+		r.Add(health.Critical, CodePostgresReplication, "", c.Resource, "no standby is replicating from the primary according to pg_stat_replication")
+	}
+
+	return r
+}
+
+// xlogLag returns the number of bytes the standby is behind the primary according to the xlog location
+// attributes. False is returned if either location is missing or invalid.
+func xlogLag(cs crm.ClusterStatus, primary, standby, attribute string) (int64, bool) {
+	p, ok := cs.NodeAttribute(primary, attribute)
+	if !ok {
+		return 0, false
+	}
+
+	s, ok := cs.NodeAttribute(standby, attribute)
+	if !ok {
+		return 0, false
+	}
+
+	pLoc, err := parseLSN(p)
+	if err != nil {
+		return 0, false
+	}
+
+	sLoc, err := parseLSN(s)
+	if err != nil {
+		return 0, false
+	}
+
+	return int64(pLoc) - int64(sLoc), true
+}
+
+// parseLSN parses a WAL location written either as `0/3000060` or as 16 hex digits (`0000000003000060`),
+// the format used by the pgsql agent.
+func parseLSN(lsn string) (uint64, error) {
+	if parts := strings.SplitN(lsn, "/", 2); len(parts) == 2 {
+		h, err := strconv.ParseUint(parts[0], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid LSN %q", lsn)
+		}
+		l, err := strconv.ParseUint(parts[1], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid LSN %q", lsn)
+		}
+		return h<<32 | l, nil
+	}
+
+	loc, err := strconv.ParseUint(lsn, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", lsn)
+	}
+
+	return loc, nil
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
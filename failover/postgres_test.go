@@ -0,0 +1,222 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// pgCluster returns the status of a two node cluster running pgsql with node1 as the primary. attrs
+// contains the node attributes of each node.
+func pgCluster(t *testing.T, node2Online bool, attrs map[string]map[string]string) crm.ClusterStatus {
+	t.Helper()
+
+	var attributes string
+	for _, node := range []string{"node1", "node2"} {
+		var keys []string
+		for k := range attrs[node] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		attributes += fmt.Sprintf(`<node name="%v">`, node)
+		for _, k := range keys {
+			attributes += fmt.Sprintf(`<attribute name="%v" value="%v"/>`, k, attrs[node][k])
+		}
+		attributes += `</node>`
+	}
+
+	doc := fmt.Sprintf(`<crm_mon version="2.0.2">
+  <summary>
+    <current_dc name="node1" with_quorum="true"/>
+    <cluster_options stonith-enabled="true"/>
+  </summary>
+  <nodes>
+    <node name="node1" online="true" type="member"/>
+    <node name="node2" online="%v" type="member"/>
+  </nodes>
+  <node_attributes>%v</node_attributes>
+</crm_mon>`, node2Online, attributes)
+
+	cs, err := crm.ParseStatus(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *cs
+}
+
+// pgFinding is a finding without its message.
+type pgFinding struct {
+	Severity health.Severity
+	Code     string
+	Node     string
+}
+
+func pgFindings(r health.Report) []pgFinding {
+	var fs []pgFinding
+	for _, f := range r.Findings {
+		fs = append(fs, pgFinding{f.Severity, f.Code, f.Node})
+	}
+	return fs
+}
+
+func TestParseLSN(t *testing.T) {
+	tests := []struct {
+		lsn     string
+		want    uint64
+		wantErr bool
+	}{
+		{"0/3000060", 0x3000060, false},
+		{"1/0", 1 << 32, false},
+		{"A/FFFFFFFF", 0xAFFFFFFFF, false},
+		{"0000000003000060", 0x3000060, false},
+		{"00000001000000A0", 0x1000000A0, false},
+		{"", 0, true},
+		{"xyz", 0, true},
+		{"0/zz", 0, true},
+		{"g/1", 0, true},
+		{"1/100000000", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLSN(tt.lsn)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseLSN(%q) = %#x, %v, want %#x, error %v", tt.lsn, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPostgresCheckAttributes(t *testing.T) {
+	healthy := func() map[string]map[string]string {
+		return map[string]map[string]string{
+			"node1": {"pgsql-data-status": "LATEST", "master-pgsql": "1000"},
+			"node2": {"pgsql-data-status": "STREAMING|SYNC", "master-pgsql": "100"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		node2Online bool
+		change      func(attrs map[string]map[string]string)
+		want        []pgFinding
+	}{
+		{"ready", true, func(map[string]map[string]string) {},
+			[]pgFinding{{health.Info, CodePostgresDataStatus, "node2"}}},
+		{"primary not latest", true, func(a map[string]map[string]string) { a["node1"]["pgsql-data-status"] = "DISCONNECT" },
+			[]pgFinding{{health.Critical, CodePostgresDataStatus, "node1"}, {health.Info, CodePostgresDataStatus, "node2"}}},
+		{"async standby", true, func(a map[string]map[string]string) { a["node2"]["pgsql-data-status"] = "STREAMING|ASYNC" },
+			[]pgFinding{{health.Critical, CodePostgresDataStatus, "node2"}}},
+		{"negative master score", true, func(a map[string]map[string]string) { a["node2"]["master-pgsql"] = "-INFINITY" },
+			[]pgFinding{{health.Critical, CodePostgresMasterScore, "node2"}, {health.Info, CodePostgresDataStatus, "node2"}}},
+		{"missing master score", true, func(a map[string]map[string]string) { delete(a["node2"], "master-pgsql") },
+			[]pgFinding{{health.Critical, CodePostgresMasterScore, "node2"}, {health.Info, CodePostgresDataStatus, "node2"}}},
+		{"xlog lag over the limit", true, func(a map[string]map[string]string) {
+			a["node1"]["pgsql-xlog-loc"] = "0000000003000060"
+			a["node2"]["pgsql-xlog-loc"] = "0000000002000060"
+		}, []pgFinding{{health.Critical, CodePostgresLag, "node2"}, {health.Info, CodePostgresDataStatus, "node2"}}},
+		{"xlog lag under the limit", true, func(a map[string]map[string]string) {
+			a["node1"]["pgsql-xlog-loc"] = "0000000003000060"
+			a["node2"]["pgsql-xlog-loc"] = "0000000003000000"
+		}, []pgFinding{{health.Info, CodePostgresDataStatus, "node2"}}},
+		{"invalid xlog location", true, func(a map[string]map[string]string) {
+			a["node1"]["pgsql-xlog-loc"] = "0000000003000060"
+			a["node2"]["pgsql-xlog-loc"] = "unknown"
+		}, []pgFinding{{health.Info, CodePostgresDataStatus, "node2"}}},
+		{"no standby", false, func(map[string]map[string]string) {},
+			[]pgFinding{{health.Critical, CodePostgresNoStandby, ""}}},
+	}
+
+	check := PostgresCheck{Resource: "pgsql", MaxLagBytes: 1 << 20}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := healthy()
+			tt.change(attrs)
+			cs := pgCluster(t, tt.node2Online, attrs)
+
+			fake := &runner.Fake{}
+			got := pgFindings(check.Check(context.Background(), fake, cs, "node1"))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+			if calls := fake.Calls(); len(calls) != 0 {
+				t.Errorf("Check() ran %v without a replication connection", calls)
+			}
+		})
+	}
+}
+
+func TestPostgresCheckReplication(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout string
+		err    error
+		want   []pgFinding
+	}{
+		{"lag under the limits", "node2|streaming|sync|1024|0.5\n", nil,
+			[]pgFinding{{health.Info, CodePostgresReplication, ""}}},
+		{"lag over max bytes", "node2|streaming|sync|2097152|0.5\n", nil,
+			[]pgFinding{{health.Critical, CodePostgresLag, ""}}},
+		{"lag over max time", "node2|streaming|sync|1024|45.2\n", nil,
+			[]pgFinding{{health.Critical, CodePostgresLag, ""}}},
+		{"async standby", "node2|streaming|async|0|0\n", nil,
+			[]pgFinding{{health.Critical, CodePostgresReplication, ""}}},
+		{"catching up", "node2|catchup|sync|0|0\n", nil,
+			[]pgFinding{{health.Critical, CodePostgresReplication, ""}}},
+		{"missing standby", "\n", nil,
+			[]pgFinding{{health.Critical, CodePostgresReplication, ""}}},
+		{"unexpected row", "node2|streaming|sync\n", nil,
+			[]pgFinding{{health.Critical, CodePostgresReplication, ""}}},
+		{"psql failure", "", errors.New("connection refused"),
+			[]pgFinding{{health.Critical, CodePostgresReplication, ""}}},
+	}
+
+	check := PostgresCheck{
+		Resource:    "pgsql",
+		MaxLagBytes: 1 << 20,
+		MaxLagTime:  30 * time.Second,
+		Replication: Replication{
+			Connection: "host=pkmdb-vip user=postgres",
+			Command:    runner.NewCommand("sudo", "-u", "postgres", "psql"),
+		},
+	}
+	cs := pgCluster(t, true, map[string]map[string]string{
+		"node1": {"pgsql-data-status": "LATEST"},
+		"node2": {"pgsql-data-status": "STREAMING|SYNC", "master-pgsql": "100"},
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+			fake := &runner.Fake{Handler: func(ctx context.Context, cmd runner.Command) (runner.Result, error) {
+				args = cmd.Args
+				res := runner.Result{Command: cmd, Stdout: tt.stdout}
+				if tt.err != nil {
+					res.ExitCode = 2
+					return res, &runner.Error{Result: res, Err: tt.err}
+				}
+				return res, nil
+			}}
+
+			r := check.Check(context.Background(), fake, cs, "node1")
+			// The first finding is the state of node2 according to its attributes.
+			if got := pgFindings(r)[1:]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+
+			wantArgs := []string{"sudo", "-u", "postgres", "psql", "-X", "-A", "-t", "-q", "-F", "|",
+				"-d", "host=pkmdb-vip user=postgres", "-c", DefaultReplicationQuery}
+			if !reflect.DeepEqual(args, wantArgs) {
+				t.Errorf("Check() ran %q, want %q", args, wantArgs)
+			}
+		})
+	}
+}
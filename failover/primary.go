@@ -0,0 +1,67 @@
+package failover
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KalebHawkins/gofailover/crm"
+)
+
+// PrimaryRule describes how the primary node of the cluster is found. Exactly one strategy is used:
+//
+//   - Attribute: the node with a node attribute set to a value, e.g. `pgsql-status=PRI`.
+//   - Group: the node running a resource group, e.g. `dwgrp`.
+//   - PromotedClone: the node running the promoted (master) instance of a promotable clone, e.g. `msPostgresql`.
+//
+// The rule can be used by any `Cluster` implementation through `PrimaryRule.Node`.
+type PrimaryRule struct {
+	Attribute     string `mapstructure:"attribute"`
+	Value         string `mapstructure:"value"`
+	Group         string `mapstructure:"group"`
+	PromotedClone string `mapstructure:"promotedClone"`
+}
+
+// Validate returns an error unless exactly one strategy is set.
+func (r PrimaryRule) Validate() error {
+	set := 0
+	for _, v := range []string{r.Attribute, r.Group, r.PromotedClone} {
+		if v != "" {
+			set++
+		}
+	}
+
+	if set == 0 {
+		return errors.New("a primary detection rule (`primary.attribute`, `primary.group` or `primary.promotedClone`) is required")
+	}
+
+	if set > 1 {
+		return errors.New("only one of `primary.attribute`, `primary.group` or `primary.promotedClone` can be set")
+	}
+
+	return nil
+}
+
+// Node returns the primary node of the cluster according to the rule. An error wrapping
+// `ErrPrimaryNotFound` is returned if there is none.
+func (r PrimaryRule) Node(cs crm.ClusterStatus) (string, error) {
+	switch {
+	case r.Group != "":
+		node, err := cs.GroupLocation(r.Group)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrPrimaryNotFound, err)
+		}
+		return node, nil
+	case r.PromotedClone != "":
+		node, err := cs.PromotedNode(r.PromotedClone)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrPrimaryNotFound, err)
+		}
+		return node, nil
+	case r.Attribute != "":
+		if node, ok := cs.NodeWithAttribute(r.Attribute, r.Value); ok {
+			return node, nil
+		}
+	}
+
+	return "", ErrPrimaryNotFound
+}
@@ -0,0 +1,354 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/health"
+	"github.com/KalebHawkins/gofailover/pgrex"
+	"github.com/KalebHawkins/gofailover/probe"
+	"github.com/KalebHawkins/gofailover/runner"
+	"github.com/KalebHawkins/gofailover/schedule"
+)
+
+// Profile is a declarative description of a cluster read from the `profiles` section of the configuration file.
+// It describes how the primary node is found, which commands perform the failover and how the result is reported.
+// Commands can be written as a command line, a list of arguments or a `runner.Command`. Commands are not run
+// through a shell.
+//
+// Example config:
+//
+//	profiles:
+//	  dw:
+//	    primary:
+//	      group: dwgrp
+//	    failover:
+//	      - pcs resource move dwgrp
+//	    settle: 25s
+//	    cleanup:
+//	      - pcs resource clear dwgrp
+//	    verify:
+//	      - command: pcs constraint location
+//	        reject: "Node:"
+//	    notification:
+//	      name: DeviceWISE
+type Profile struct {
+	Primary    PrimaryRule      `mapstructure:"primary"`
+	Failover   []runner.Command `mapstructure:"failover"`
+	Switchover pgrex.Switchover `mapstructure:"switchover"`
+	Converge   Convergence      `mapstructure:"converge"`
+	Cleanup    []runner.Command `mapstructure:"cleanup"`
+	Verify     []Verification   `mapstructure:"verify"`
+	Probes     []probe.Probe    `mapstructure:"probes"`
+	// Rollback fails the cluster back to the original primary node if the post-failover checks or probes fail.
+	Rollback bool `mapstructure:"rollback"`
+	// Schedule replaces the rules of the `schedule` section of the configuration file for this profile.
+	// Its blackouts are added to the ones of the configuration file.
+	Schedule     schedule.Schedule `mapstructure:"schedule"`
+	HealthCheck  HealthCheck       `mapstructure:"healthCheck"`
+	Postgres     PostgresCheck     `mapstructure:"postgres"`
+	Timeouts     Timeouts          `mapstructure:"timeouts"`
+	Notification Notification      `mapstructure:"notification"`
+}
+
+// Verification is a command run after the failover and cleanup commands. The failover is considered
+// failed if the output of the command contains the `Reject` string.
+type Verification struct {
+	Command runner.Command `mapstructure:"command"`
+	Reject  string         `mapstructure:"reject"`
+	Message string         `mapstructure:"message"`
+}
+
+// HealthCheck contains the tweaks applied to the health check of a profile.
+type HealthCheck struct {
+	// Command returns the `crm_mon` xml output of the cluster. By default the command is chosen for the
+	// installed Pacemaker version, see `crm.StatusArgs`.
+	Command runner.Command `mapstructure:"command"`
+	// IgnoreResources is a list of resource ids or glob patterns that are not checked, in addition to the
+	// ones ignored by the `healthPolicy` section of the configuration file.
+	IgnoreResources []string `mapstructure:"ignoreResources"`
+}
+
+// Timeouts bounds how long each command of a profile may run before it is killed.
+// A zero timeout means the command is never killed.
+type Timeouts struct {
+	// Status bounds the command pulling the cluster status (`crm_mon`).
+	Status time.Duration `mapstructure:"status"`
+	// Failover bounds each failover command, e.g. `pcs resource move` or `pg-rex_switchover`.
+	Failover time.Duration `mapstructure:"failover"`
+	// Cleanup bounds each cleanup and verify command, e.g. `pcs resource clear`.
+	Cleanup time.Duration `mapstructure:"cleanup"`
+}
+
+// Notification contains the text used in the notifications of a profile. The messages are
+// `text/template` templates, see `MessageData` for the fields available to them.
+type Notification struct {
+	Name           string `mapstructure:"name"`
+	Subject        string `mapstructure:"subject"`
+	Success        string `mapstructure:"success"`
+	Error          string `mapstructure:"error"`
+	Aborted        string `mapstructure:"aborted"`
+	RolledBack     string `mapstructure:"rolledBack"`
+	RollbackFailed string `mapstructure:"rollbackFailed"`
+	Skipped        string `mapstructure:"skipped"`
+}
+
+// Validate returns an error if the profile is missing required settings.
+func (p Profile) Validate() error {
+	if err := p.Primary.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+
+	if len(p.Failover) == 0 && !p.Switchover.Enabled {
+		return fmt.Errorf("%w: at least one `failover` command or the `switchover` is required", ErrInvalidProfile)
+	}
+
+	if err := p.Switchover.Validate(); err != nil {
+		return fmt.Errorf("%w: switchover: %v", ErrInvalidProfile, err)
+	}
+
+	for _, cmd := range append(append([]runner.Command{}, p.Failover...), p.Cleanup...) {
+		if len(cmd.Args) == 0 {
+			return fmt.Errorf("%w: empty command", ErrInvalidProfile)
+		}
+	}
+
+	for _, v := range p.Verify {
+		if len(v.Command.Args) == 0 {
+			return fmt.Errorf("%w: empty verify command", ErrInvalidProfile)
+		}
+	}
+
+	for _, pr := range p.Probes {
+		if err := pr.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+		}
+	}
+
+	if err := p.Schedule.Validate(); err != nil {
+		return fmt.Errorf("%w: schedule: %v", ErrInvalidProfile, err)
+	}
+
+	for _, text := range []string{p.Notification.Success, p.Notification.Error, p.Notification.Aborted, p.Notification.RolledBack, p.Notification.RollbackFailed, p.Notification.Skipped} {
+		if _, err := template.New("message").Parse(text); err != nil {
+			return fmt.Errorf("%w: invalid notification template: %v", ErrInvalidProfile, err)
+		}
+	}
+
+	return nil
+}
+
+// ProfileCluster implements the `Cluster` interface for a `Profile`.
+type ProfileCluster struct {
+	Profile  Profile
+	Executor runner.Executor
+	// Status returns the current status of the cluster. It is polled while waiting for the cluster to converge.
+	Status func(ctx context.Context) (crm.ClusterStatus, error)
+}
+
+// ProfileCluster.Name() returns the notification name of the profile.
+func (pc *ProfileCluster) Name() string {
+	return pc.Profile.Notification.Name
+}
+
+// ProfileCluster.PrimaryNode() returns the cluster's current primary node using the profile's primary detection rule.
+func (pc *ProfileCluster) PrimaryNode(cs crm.ClusterStatus) (string, error) {
+	return pc.Profile.Primary.Node(cs)
+}
+
+// ProfileCluster.PreCheck() runs the PostgreSQL checks of the profile, if any, against the current primary node.
+func (pc *ProfileCluster) PreCheck(ctx context.Context, cs crm.ClusterStatus) health.Report {
+	if !pc.Profile.Postgres.Enabled() {
+		return health.Report{}
+	}
+
+	// The engine reports a missing primary node on its own.
+	primary, err := pc.PrimaryNode(cs)
+	if err != nil {
+		return health.Report{}
+	}
+
+	if timeout := pc.Profile.Timeouts.Status; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return pc.Profile.Postgres.Check(ctx, pc.Executor, cs, primary)
+}
+
+// ProfileCluster.Failover() runs the failover commands and the `pg-rex_switchover` driver, if enabled, waits for
+// the cluster to converge on the new primary, runs the cleanup commands and finally runs the verification commands
+// of the profile.
+func (pc *ProfileCluster) Failover(ctx context.Context, cs crm.ClusterStatus) error {
+	oldPrimary, err := pc.PrimaryNode(cs)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range pc.Profile.Failover {
+		if _, err := pc.run(ctx, cmd, pc.Profile.Timeouts.Failover); err != nil {
+			return err
+		}
+	}
+
+	var switchover pgrex.Result
+	if pc.Profile.Switchover.Enabled {
+		if switchover, err = pc.switchover(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Some failovers, like moving a resource group, return before the resources are moved. Cleaning up
+	// too early (e.g. clearing location constraints) would move the resources straight back.
+	converger := &Converger{Convergence: pc.Profile.Converge, Status: pc.Status, Executor: pc.Executor}
+	cs, err = converger.Wait(ctx, func(cs crm.ClusterStatus) (bool, string) {
+		return pc.movedFrom(cs, oldPrimary)
+	})
+	if err != nil {
+		return err
+	}
+
+	if primary, _ := pc.PrimaryNode(cs); switchover.NewPrimary != "" && primary != switchover.NewPrimary {
+		return fmt.Errorf("pg-rex_switchover reported %v as the new primary but the cluster is running it on %v", switchover.NewPrimary, primary)
+	}
+
+	return pc.Cleanup(ctx)
+}
+
+// switchover runs the `pg-rex_switchover` driver of the profile.
+func (pc *ProfileCluster) switchover(ctx context.Context) (pgrex.Result, error) {
+	if timeout := pc.Profile.Timeouts.Failover; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return pc.Profile.Switchover.Run(ctx, pc.Executor)
+}
+
+// ProfileCluster.Plan() returns the steps `Failover` would perform, followed by the post-failover checks.
+func (pc *ProfileCluster) Plan(cs crm.ClusterStatus) []PlannedStep {
+	p := pc.Profile
+	oldPrimary, _ := pc.PrimaryNode(cs)
+
+	var steps []PlannedStep
+	for _, cmd := range p.Failover {
+		steps = append(steps, PlannedStep{Command: cmd.String(), Mutating: true,
+			Reason: fmt.Sprintf("move the primary role away from %v (timeout %v)", oldPrimary, p.Timeouts.Failover)})
+	}
+
+	if p.Switchover.Enabled {
+		cmd := p.Switchover.Command.String()
+		if cmd == "" {
+			cmd = "pg-rex_switchover"
+		}
+		steps = append(steps, PlannedStep{Command: cmd, Mutating: true,
+			Reason: fmt.Sprintf("switch the primary role over from %v to the standby, answering only the expected prompts (timeout %v)", oldPrimary, p.Timeouts.Failover)})
+	}
+
+	interval, timeout := p.Converge.Interval, p.Converge.Timeout
+	if interval <= 0 {
+		interval = DefaultConvergeInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultConvergeTimeout
+	}
+	steps = append(steps, PlannedStep{Reason: fmt.Sprintf("wait up to %v, polling every %v, for the primary role to leave %v and the cluster to settle", timeout, interval, oldPrimary)})
+
+	for _, cmd := range p.Cleanup {
+		steps = append(steps, PlannedStep{Command: cmd.String(), Mutating: true,
+			Reason: fmt.Sprintf("clean up after the failover (timeout %v)", p.Timeouts.Cleanup)})
+	}
+
+	for _, v := range p.Verify {
+		reason := "verify the cleanup"
+		if v.Reject != "" {
+			reason = fmt.Sprintf("verify the cleanup, fails if the output contains %q", v.Reject)
+		}
+		steps = append(steps, PlannedStep{Command: v.Command.String(), Reason: reason})
+	}
+
+	steps = append(steps, PlannedStep{Reason: "check the health of the cluster again"})
+
+	for _, pr := range p.Probes {
+		steps = append(steps, PlannedStep{Reason: fmt.Sprintf("probe %v", pr)})
+	}
+
+	if p.Rollback {
+		steps = append(steps, PlannedStep{Reason: fmt.Sprintf("if the checks fail, run the failover again once to restore %v as the primary node", oldPrimary)})
+	}
+
+	return steps
+}
+
+// ProfileCluster.Probe() runs the probes of the profile.
+func (pc *ProfileCluster) Probe(ctx context.Context) probe.Results {
+	return probe.RunAll(ctx, pc.Executor, pc.Profile.Probes)
+}
+
+// movedFrom returns true once the primary role has moved away from the old primary node. For group based
+// profiles every resource of the group has to be active on the new primary node.
+func (pc *ProfileCluster) movedFrom(cs crm.ClusterStatus, oldPrimary string) (bool, string) {
+	primary, err := pc.PrimaryNode(cs)
+	if err != nil {
+		return false, "no primary node found"
+	}
+
+	if primary == oldPrimary {
+		return false, fmt.Sprintf("the primary node is still %v", oldPrimary)
+	}
+
+	if g := cs.FindResource(pc.Profile.Primary.Group); g != nil {
+		for _, r := range g.Primitives() {
+			if !r.Active || !r.RunsOn(primary) {
+				return false, fmt.Sprintf("resource %v of group %v is not active on %v", r.ID, g.ID, primary)
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// ProfileCluster.Cleanup() runs the cleanup commands followed by the verification commands of the profile.
+// It is also called when a failover is interrupted so the cluster is not left with e.g. a location constraint.
+func (pc *ProfileCluster) Cleanup(ctx context.Context) error {
+	for _, cmd := range pc.Profile.Cleanup {
+		if _, err := pc.run(ctx, cmd, pc.Profile.Timeouts.Cleanup); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range pc.Profile.Verify {
+		res, err := pc.run(ctx, v.Command, pc.Profile.Timeouts.Cleanup)
+		if err != nil {
+			return err
+		}
+
+		if v.Reject != "" && strings.Contains(res.Stdout, v.Reject) {
+			msg := v.Message
+			if msg == "" {
+				msg = fmt.Sprintf("verification command `%v` returned unexpected output", v.Command)
+			}
+			return fmt.Errorf("%v:\n%v", msg, res.Stdout)
+		}
+	}
+
+	return nil
+}
+
+// run executes a command, streaming its output, and kills it if it runs longer than the timeout.
+func (pc *ProfileCluster) run(ctx context.Context, cmd runner.Command, timeout time.Duration) (runner.Result, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd.Stream = true
+	return pc.Executor.Run(ctx, cmd)
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// CheckDC returns a critical finding if the DC is not idle according to `crmadmin -S`, e.g. while it is
+// still computing or running a transition. Nothing is checked if the cluster has no DC, `Evaluate` reports it.
+func CheckDC(ctx context.Context, executor runner.Executor, cs crm.ClusterStatus) Report {
+	var r Report
+
+	dc := cs.Status.DesignatedController.Node
+	if dc == "" {
+		return r
+	}
+
+	res, err := executor.Run(ctx, runner.NewCommand("crmadmin", "-S", dc))
+	if err != nil {
+		r.Add(Critical, CodeDCBusy, dc, "", "unable to get the state of the DC %v: %v", dc, err)
+		return r
+	}
+
+	// Older versions print `Status of crmd@node1: S_IDLE (ok)`, newer ones
+	// `Controller on node1 in state S_IDLE: ok`. Some versions print to stderr.
+	out := res.Stdout + res.Stderr
+	if !strings.Contains(out, "S_IDLE") {
+		r.Add(Critical, CodeDCBusy, dc, "", "the DC %v is not idle: %v", dc, strings.TrimSpace(out))
+	}
+
+	return r
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+func TestCheckDC(t *testing.T) {
+	tests := []struct {
+		name   string
+		dc     string
+		result *runner.Result
+		want   []finding
+	}{
+		{"idle", "node1", &runner.Result{Stdout: "Controller on node1 in state S_IDLE: ok\n"}, nil},
+		{"idle on an older version", "node1", &runner.Result{Stdout: "Status of crmd@node1: S_IDLE (ok)\n"}, nil},
+		{"idle on stderr", "node1", &runner.Result{Stderr: "Status of crmd@node1: S_IDLE (ok)\n"}, nil},
+		{"busy", "node1", &runner.Result{Stdout: "Controller on node1 in state S_TRANSITION_ENGINE: ok\n"},
+			[]finding{{Critical, CodeDCBusy, "node1", ""}}},
+		// The DC changed since crm_mon ran, node1 is no longer the DC.
+		{"dc mismatch", "node1", &runner.Result{Stdout: "Controller on node1 in state S_NOT_DC: ok\n"},
+			[]finding{{Critical, CodeDCBusy, "node1", ""}}},
+		{"crmadmin failure", "node1", &runner.Result{ExitCode: 1}, []finding{{Critical, CodeDCBusy, "node1", ""}}},
+		{"no dc", "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := parseFixture(t, "healthy.xml")
+			cs.Status.DesignatedController.Node = tt.dc
+
+			fake := &runner.Fake{}
+			if tt.result != nil {
+				fake.Results = map[string]runner.Result{"crmadmin -S " + tt.dc: *tt.result}
+			}
+
+			if got := findings(CheckDC(context.Background(), fake, cs)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckDC() = %v, want %v", got, tt.want)
+			}
+			if tt.result == nil && len(fake.Calls()) != 0 {
+				t.Errorf("CheckDC() ran %v, want no command", fake.Calls())
+			}
+		})
+	}
+}
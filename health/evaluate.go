@@ -0,0 +1,190 @@
+package health
+
+import (
+	"strings"
+
+	"github.com/KalebHawkins/gofailover/crm"
+)
+
+// Evaluate checks the cluster options, the nodes, the resources and the fail counts of the cluster and
+// returns every problem found. Node attributes are not checked, that is up to each cluster. The state of
+// the DC is checked separately by `CheckDC` as it requires running `crmadmin`.
+//
+// A cluster without quorum or DC, with STONITH disabled, in maintenance mode or with all resources stopped is critical.
+// Nodes that are offline, in standby, in maintenance, pending, unclean or shutting down are critical.
+// Resources that are failed, blocked, unmanaged or inactive are critical, unless they are disabled or only
+// some of the instances of a clone are inactive. Orphaned resources and failed actions are warnings.
+func Evaluate(cs crm.ClusterStatus) Report {
+	var r Report
+
+	evaluateCluster(&r, cs.Status)
+
+	for _, n := range cs.Nodes {
+		evaluateNode(&r, n)
+	}
+
+	for _, p := range cs.Resources.Primitives() {
+		evaluateResource(&r, p)
+	}
+
+	// A resource that reached its migration threshold on a node is banned from it until the failures are cleaned up.
+	for _, n := range cs.History {
+		for _, h := range n.Resources {
+			if h.FailuresLeft() == 0 {
+				r.Add(Critical, CodeMigrationThreshold, n.Node, h.ID, "%v has failed %v time(s) on %v with a migration threshold of %v and is banned from the node",
+					h.ID, h.FailCount, n.Node, h.MigrationThreshold)
+			}
+		}
+	}
+
+	for _, f := range cs.Failures {
+		reason := f.ExitStatus
+		if f.ExitReason != "" {
+			reason += ", " + f.ExitReason
+		}
+		r.Add(Warning, CodeFailedAction, f.Node, resourceOf(f), "action %v failed on %v: %v", f.OpKey, f.Node, reason)
+	}
+
+	return r
+}
+
+// MigrationThresholds returns a critical finding for each resource that already failed on one of the nodes
+// the primary role would move to and is one failure away from its migration threshold there. A failure during
+// the failover would then ban the resource from that node. `primary` is the current primary node, every other
+// online node is considered a target.
+func MigrationThresholds(cs crm.ClusterStatus, primary string) Report {
+	var r Report
+
+	for _, n := range cs.Nodes {
+		if n.Name == primary || !n.Online {
+			continue
+		}
+
+		for _, h := range cs.ResourcesOnNodeHistory(n.Name) {
+			if h.FailCount > 0 && h.FailuresLeft() >= 0 && h.FailuresLeft() <= 1 {
+				r.Add(Critical, CodeMigrationThreshold, n.Name, h.ID,
+					"%v on %v has failed %v time(s) with a migration threshold of %v, a failover could ban the resource from the node. Please clean up the failures (`pcs resource cleanup`) first",
+					h.ID, n.Name, h.FailCount, h.MigrationThreshold)
+			}
+		}
+	}
+
+	return r
+}
+
+func evaluateCluster(r *Report, s crm.Summary) {
+	if s.DesignatedController.Node == "" {
+		r.Add(Critical, CodeClusterNoDC, "", "", "the cluster has no DC")
+	}
+	if !s.DesignatedController.Quorum {
+		r.Add(Critical, CodeClusterNoQuorum, "", "", "the cluster does not have quorum")
+	}
+	if !s.Options.StonithEnabled {
+		r.Add(Critical, CodeStonithDisabled, "", "", "STONITH is disabled, a node failing during the failover could not be fenced")
+	}
+	if s.Options.MaintenanceMode {
+		r.Add(Critical, CodeClusterMaintenance, "", "", "the cluster is in maintenance mode, it does not manage any resource")
+	}
+	if s.Options.StopAllResources {
+		r.Add(Critical, CodeClusterStopAll, "", "", "the cluster is configured to stop all resources")
+	}
+}
+
+func evaluateNode(r *Report, n crm.Node) {
+	switch {
+	case !n.Online:
+		r.Add(Critical, CodeNodeOffline, n.Name, "", "node %v is offline", n.Name)
+	case n.Unclean:
+		r.Add(Critical, CodeNodeUnclean, n.Name, "", "node %v is unclean and may be fenced", n.Name)
+	case n.Pending:
+		r.Add(Critical, CodeNodePending, n.Name, "", "node %v is pending and has not joined the cluster yet", n.Name)
+	case n.Shutdown:
+		r.Add(Critical, CodeNodeShutdown, n.Name, "", "node %v is shutting down", n.Name)
+	}
+
+	if n.Online && n.Standby {
+		r.Add(Critical, CodeNodeStandby, n.Name, "", "node %v is in standby and cannot run resources", n.Name)
+	}
+	if n.Maintenance {
+		r.Add(Critical, CodeNodeMaintenance, n.Name, "", "node %v is in maintenance mode, the cluster does not manage its resources", n.Name)
+	}
+}
+
+func evaluateResource(r *Report, p *crm.Resource) {
+	node := p.Node()
+
+	if p.Failed && !p.FailureIgnored {
+		r.Add(Critical, CodeResourceFailed, node, p.ID, "resource %v has failed%v", p.ID, on(node))
+	}
+	if p.Blocked {
+		r.Add(Critical, CodeResourceBlocked, node, p.ID, "resource %v is blocked%v, the cluster cannot recover it", p.ID, on(node))
+	}
+	if !p.Managed {
+		r.Add(Critical, CodeResourceUnmanaged, node, p.ID, "resource %v is not managed by the cluster%v, it is unmanaged or in maintenance", p.ID, on(node))
+	}
+	if p.Orphaned {
+		r.Add(Warning, CodeResourceOrphaned, node, p.ID, "resource %v is orphaned, it is no longer configured but still has a status%v", p.ID, on(node))
+	}
+
+	if p.Active || p.Orphaned {
+		return
+	}
+
+	switch clone := cloneOf(p); {
+	case disabled(p):
+		r.Add(Info, CodeResourceInactive, "", p.ID, "resource %v is disabled and not running", p.ID)
+	case clone != nil && anyActive(clone):
+		r.Add(Warning, CodeResourceInactive, "", p.ID, "an instance of %v (%v) is not running", clone.ID, p.ID)
+	default:
+		r.Add(Critical, CodeResourceInactive, "", p.ID, "resource %v is not running", p.ID)
+	}
+}
+
+// disabled returns true if the resource or one of its parents has a target role of `Stopped`.
+func disabled(r *crm.Resource) bool {
+	for ; r != nil; r = r.Parent {
+		if r.TargetRole == crm.RoleStopped {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cloneOf returns the clone the resource is an instance of or nil.
+func cloneOf(r *crm.Resource) *crm.Resource {
+	for p := r.Parent; p != nil; p = p.Parent {
+		if p.Kind == crm.KindClone {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func anyActive(r *crm.Resource) bool {
+	for _, p := range r.Primitives() {
+		if p.Active {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourceOf returns the id of the resource of a failed action, e.g. `pgsql` for `pgsql_monitor_10000`.
+func resourceOf(f crm.Failure) string {
+	if i := strings.LastIndex(f.OpKey, "_"+f.Task+"_"); i > 0 {
+		return f.OpKey[:i]
+	}
+
+	return f.OpKey
+}
+
+func on(node string) string {
+	if node == "" {
+		return ""
+	}
+
+	return " on " + node
+}
@@ -0,0 +1,175 @@
+package health
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/KalebHawkins/gofailover/crm"
+)
+
+// finding is a finding without its message, the messages are not worth asserting.
+type finding struct {
+	Severity Severity
+	Code     string
+	Node     string
+	Resource string
+}
+
+func findings(r Report) []finding {
+	var fs []finding
+	for _, f := range r.Findings {
+		fs = append(fs, finding{f.Severity, f.Code, f.Node, f.Resource})
+	}
+	return fs
+}
+
+func parseFixture(t *testing.T, name string) crm.ClusterStatus {
+	t.Helper()
+
+	cs, err := crm.ParseStatusFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *cs
+}
+
+func TestEvaluateHealthy(t *testing.T) {
+	r := Evaluate(parseFixture(t, "healthy.xml"))
+	if len(r.Findings) != 0 {
+		t.Errorf("Evaluate(healthy.xml) = %v, want no findings", r.Findings)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Report.Err() = %v, want nil", err)
+	}
+}
+
+func TestEvaluateDegraded(t *testing.T) {
+	r := Evaluate(parseFixture(t, "degraded.xml"))
+
+	want := []finding{
+		{Critical, CodeNodeStandby, "node2", ""},
+		{Critical, CodeNodeOffline, "node3", ""},
+		{Critical, CodeResourceFailed, "node1", "app"},
+		{Info, CodeResourceInactive, "", "backup"},
+		{Critical, CodeResourceInactive, "", "report"},
+		{Critical, CodeResourceBlocked, "node1", "batch"},
+		{Critical, CodeResourceUnmanaged, "node1", "legacy"},
+		{Warning, CodeResourceOrphaned, "", "old-app"},
+		{Warning, CodeResourceInactive, "", "ping"},
+		{Critical, CodeMigrationThreshold, "node1", "app"},
+		{Warning, CodeFailedAction, "node1", "app"},
+	}
+	if got := findings(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("Evaluate(degraded.xml) =\n%v\nwant\n%v", got, want)
+	}
+
+	err := r.Err()
+	for _, target := range []error{ErrUnhealthyNode, ErrUnhealthyResource, ErrMigrationThreshold} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) = false, want true", err, target)
+		}
+	}
+}
+
+func TestEvaluateNodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(n *crm.Node)
+		want   []finding
+	}{
+		{"offline", func(n *crm.Node) { n.Online = false }, []finding{{Critical, CodeNodeOffline, "node2", ""}}},
+		{"unclean", func(n *crm.Node) { n.Unclean = true }, []finding{{Critical, CodeNodeUnclean, "node2", ""}}},
+		{"pending", func(n *crm.Node) { n.Pending = true }, []finding{{Critical, CodeNodePending, "node2", ""}}},
+		{"shutdown", func(n *crm.Node) { n.Shutdown = true }, []finding{{Critical, CodeNodeShutdown, "node2", ""}}},
+		{"standby", func(n *crm.Node) { n.Standby = true }, []finding{{Critical, CodeNodeStandby, "node2", ""}}},
+		{"maintenance", func(n *crm.Node) { n.Maintenance = true }, []finding{{Critical, CodeNodeMaintenance, "node2", ""}}},
+		{"offline in standby", func(n *crm.Node) { n.Online, n.Standby = false, true }, []finding{{Critical, CodeNodeOffline, "node2", ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := parseFixture(t, "healthy.xml")
+			tt.change(&cs.Nodes[1])
+
+			if got := findings(Evaluate(cs)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCluster(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cs *crm.ClusterStatus)
+		want   []finding
+	}{
+		{"no quorum", func(cs *crm.ClusterStatus) { cs.Status.DesignatedController.Quorum = false },
+			[]finding{{Critical, CodeClusterNoQuorum, "", ""}}},
+		{"no dc", func(cs *crm.ClusterStatus) { cs.Status.DesignatedController.Node = "" },
+			[]finding{{Critical, CodeClusterNoDC, "", ""}}},
+		{"stonith disabled", func(cs *crm.ClusterStatus) { cs.Status.Options.StonithEnabled = false },
+			[]finding{{Critical, CodeStonithDisabled, "", ""}}},
+		{"maintenance mode", func(cs *crm.ClusterStatus) { cs.Status.Options.MaintenanceMode = true },
+			[]finding{{Critical, CodeClusterMaintenance, "", ""}}},
+		{"stop all resources", func(cs *crm.ClusterStatus) { cs.Status.Options.StopAllResources = true },
+			[]finding{{Critical, CodeClusterStopAll, "", ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := parseFixture(t, "healthy.xml")
+			tt.change(&cs)
+
+			r := Evaluate(cs)
+			if got := findings(r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+			if err := r.Err(); !errors.Is(err, ErrUnhealthyCluster) {
+				t.Errorf("errors.Is(%v, ErrUnhealthyCluster) = false, want true", err)
+			}
+		})
+	}
+}
+
+// TestEvaluateUnhealthyCluster checks a cluster without quorum, without STONITH and in maintenance mode
+// as crm_mon reports it.
+func TestEvaluateUnhealthyCluster(t *testing.T) {
+	r := Evaluate(parseFixture(t, "unhealthy-cluster.xml"))
+
+	want := []finding{
+		{Critical, CodeClusterNoQuorum, "", ""},
+		{Critical, CodeStonithDisabled, "", ""},
+		{Critical, CodeClusterMaintenance, "", ""},
+		{Critical, CodeNodeOffline, "node2", ""},
+		{Critical, CodeResourceUnmanaged, "node1", "vip"},
+		{Critical, CodeResourceUnmanaged, "node1", "app"},
+	}
+	if got := findings(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("Evaluate(unhealthy-cluster.xml) =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestMigrationThresholds(t *testing.T) {
+	tests := []struct {
+		fixture string
+		primary string
+		want    []finding
+	}{
+		// vip failed twice on node2 with a threshold of 3, node3 is offline.
+		{"degraded.xml", "node1", []finding{{Critical, CodeMigrationThreshold, "node2", "vip"}}},
+		// app is already banned from node1.
+		{"degraded.xml", "node2", []finding{{Critical, CodeMigrationThreshold, "node1", "app"}}},
+		// vip has a threshold but no failures.
+		{"healthy.xml", "node1", nil},
+	}
+
+	for _, tt := range tests {
+		got := findings(MigrationThresholds(parseFixture(t, tt.fixture), tt.primary))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MigrationThresholds(%v, %v) = %v, want %v", tt.fixture, tt.primary, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package health
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/KalebHawkins/gofailover/crm"
+)
+
+// Codes of the findings added by a `Policy`.
+const (
+	CodeResourceRequired = "resource-required"
+	CodeCloneInstances   = "clone-instances"
+)
+
+// Policy tailors the health check to a cluster. Resources and codes can be matched with glob patterns
+// (see `path.Match`). A pattern matching a group or a clone also matches the resources it contains.
+//
+// Example config:
+//
+//	healthPolicy:
+//	  ignore:
+//	    - monitoring-clone
+//	    - "*-test"
+//	  overrides:
+//	    - code: failed-action
+//	      severity: info
+//	    - resource: backup
+//	      code: resource-inactive
+//	      severity: info
+//	  requireActive:
+//	    - vip
+//	  minInstances:
+//	    - clone: ping-clone
+//	      count: 2
+type Policy struct {
+	// Ignore drops every finding about the resources.
+	Ignore []string `mapstructure:"ignore"`
+	// Overrides change the severity of matching findings. When several overrides match a finding
+	// the last one wins.
+	Overrides []Override `mapstructure:"overrides"`
+	// RequireActive adds a critical finding for each resource that is not running, even if it is
+	// disabled or ignored.
+	RequireActive []string `mapstructure:"requireActive"`
+	// MinInstances adds a critical finding for each clone running fewer instances than required.
+	MinInstances []MinInstances `mapstructure:"minInstances"`
+}
+
+// Override changes the severity of the findings matching all of its non-empty patterns. `Severity` is
+// required, it is a pointer so an override without one is rejected instead of turning the findings into `Info`.
+type Override struct {
+	Code     string    `mapstructure:"code"`
+	Resource string    `mapstructure:"resource"`
+	Node     string    `mapstructure:"node"`
+	Severity *Severity `mapstructure:"severity"`
+}
+
+// MinInstances is the minimum number of running instances of a clone.
+type MinInstances struct {
+	Clone string `mapstructure:"clone"`
+	Count int    `mapstructure:"count"`
+}
+
+// Validate returns an error if a pattern of the policy is invalid.
+func (p Policy) Validate() error {
+	patterns := append([]string{}, p.Ignore...)
+	for i, o := range p.Overrides {
+		if o.Code == "" && o.Resource == "" && o.Node == "" {
+			return fmt.Errorf("override %v must match a code, a resource or a node", i+1)
+		}
+		if o.Severity == nil {
+			return fmt.Errorf("override %v must set a severity", i+1)
+		}
+		patterns = append(patterns, o.Code, o.Resource, o.Node)
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	for _, m := range p.MinInstances {
+		if m.Clone == "" || m.Count < 1 {
+			return fmt.Errorf("minInstances entries need a clone and a count of at least 1")
+		}
+	}
+
+	return nil
+}
+
+// Evaluate evaluates the health of the cluster, see `Evaluate`, and applies the policy to the report.
+func (p Policy) Evaluate(cs crm.ClusterStatus) Report {
+	return p.Apply(cs, Evaluate(cs))
+}
+
+// Apply applies the policy to a report of the cluster: ignored findings are dropped, the overrides
+// are applied and the findings of the requirements are added.
+func (p Policy) Apply(cs crm.ClusterStatus, report Report) Report {
+	r := p.Filter(cs, report)
+	r.Merge(p.Requirements(cs))
+
+	return r
+}
+
+// Filter drops the ignored findings of the report and applies the overrides to the others.
+func (p Policy) Filter(cs crm.ClusterStatus, report Report) Report {
+	var r Report
+
+	for _, f := range report.Findings {
+		if p.ignored(cs, f) {
+			continue
+		}
+
+		for _, o := range p.Overrides {
+			if o.Severity != nil && o.matches(cs, f) {
+				f.Severity = *o.Severity
+			}
+		}
+
+		r.Findings = append(r.Findings, f)
+	}
+
+	return r
+}
+
+// Requirements returns a critical finding for each required resource that is not running and each clone
+// running fewer instances than required.
+func (p Policy) Requirements(cs crm.ClusterStatus) Report {
+	var r Report
+
+	for _, id := range p.RequireActive {
+		configured, running := false, false
+		cs.Resources.Walk(func(res *crm.Resource) {
+			if res.ID == id {
+				configured = true
+				running = running || activeInstances(res) > 0
+			}
+		})
+
+		switch {
+		case !configured:
+			r.Add(Critical, CodeResourceRequired, "", id, "required resource %v is not configured", id)
+		case !running:
+			r.Add(Critical, CodeResourceRequired, "", id, "required resource %v is not running", id)
+		}
+	}
+
+	for _, m := range p.MinInstances {
+		res := cs.FindResource(m.Clone)
+		switch {
+		case res == nil || res.Kind != crm.KindClone:
+			r.Add(Critical, CodeCloneInstances, "", m.Clone, "clone %v is not configured", m.Clone)
+		case activeInstances(res) < m.Count:
+			r.Add(Critical, CodeCloneInstances, "", m.Clone, "clone %v is running %v instance(s), at least %v are required", m.Clone, activeInstances(res), m.Count)
+		}
+	}
+
+	return r
+}
+
+func (p Policy) ignored(cs crm.ClusterStatus, f Finding) bool {
+	for _, pattern := range p.Ignore {
+		if matchesResource(cs, pattern, f.Resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o Override) matches(cs crm.ClusterStatus, f Finding) bool {
+	return match(o.Code, f.Code) && match(o.Node, f.Node) && (o.Resource == "" || matchesResource(cs, o.Resource, f.Resource))
+}
+
+// match returns true if the pattern is empty or matches the value.
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// matchesResource returns true if the pattern matches the resource or one of its parents.
+func matchesResource(cs crm.ClusterStatus, pattern, id string) bool {
+	if id == "" {
+		return false
+	}
+
+	if match(pattern, id) {
+		return true
+	}
+
+	res := cs.FindResource(id)
+	if res == nil {
+		return false
+	}
+
+	for p := res.Parent; p != nil; p = p.Parent {
+		if match(pattern, p.ID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// activeInstances returns the number of running instances of a clone, or 1 if any primitive of another
+// kind of resource is running. An instance of a clone of groups is running if all its primitives are.
+func activeInstances(r *crm.Resource) int {
+	if r.Kind != crm.KindClone {
+		if anyActive(r) {
+			return 1
+		}
+		return 0
+	}
+
+	var n int
+	for _, instance := range r.Children {
+		primitives := instance.Primitives()
+		running := len(primitives) > 0
+		for _, p := range primitives {
+			running = running && p.Active
+		}
+		if running {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,196 @@
+package health
+
+import (
+	"reflect"
+	"testing"
+)
+
+func severity(s Severity) *Severity {
+	return &s
+}
+
+// severityOf returns the severity of the first finding with the code about the resource, or node if
+// resource is empty, and false if there is none.
+func severityOf(r Report, code, resource string) (Severity, bool) {
+	for _, f := range r.Findings {
+		if f.Code == code && (f.Resource == resource || f.Resource == "" && f.Node == resource) {
+			return f.Severity, true
+		}
+	}
+	return Info, false
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{"empty", Policy{}, false},
+		{"valid", Policy{
+			Ignore:        []string{"*-test"},
+			Overrides:     []Override{{Code: "failed-action", Severity: severity(Info)}},
+			RequireActive: []string{"vip"},
+			MinInstances:  []MinInstances{{Clone: "ping-clone", Count: 2}},
+		}, false},
+		{"override without pattern", Policy{Overrides: []Override{{Severity: severity(Info)}}}, true},
+		{"override without severity", Policy{Overrides: []Override{{Code: "node-offline"}}}, true},
+		{"invalid ignore pattern", Policy{Ignore: []string{"[vip"}}, true},
+		{"invalid override pattern", Policy{Overrides: []Override{{Resource: "[vip", Severity: severity(Info)}}}, true},
+		{"minInstances without clone", Policy{MinInstances: []MinInstances{{Count: 2}}}, true},
+		{"minInstances without count", Policy{MinInstances: []MinInstances{{Clone: "ping-clone"}}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%v: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	type check struct {
+		code, resource string
+		// present is false if the policy must drop the finding.
+		present  bool
+		severity Severity
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		checks []check
+	}{
+		{"no policy", Policy{}, []check{
+			{CodeResourceFailed, "app", true, Critical},
+			{CodeFailedAction, "app", true, Warning},
+			{CodeResourceInactive, "backup", true, Info},
+		}},
+		{"ignore a group", Policy{Ignore: []string{"dwgrp"}}, []check{
+			{CodeResourceFailed, "app", false, 0},
+			{CodeMigrationThreshold, "app", false, 0},
+			{CodeFailedAction, "app", false, 0},
+			{CodeResourceInactive, "report", true, Critical},
+		}},
+		{"ignore a clone", Policy{Ignore: []string{"ping-clone"}}, []check{
+			{CodeResourceInactive, "ping", false, 0},
+		}},
+		{"ignore a glob", Policy{Ignore: []string{"*-app"}}, []check{
+			{CodeResourceOrphaned, "old-app", false, 0},
+			{CodeResourceFailed, "app", true, Critical},
+		}},
+		{"override a code", Policy{Overrides: []Override{{Code: "failed-action", Severity: severity(Info)}}}, []check{
+			{CodeFailedAction, "app", true, Info},
+			{CodeResourceFailed, "app", true, Critical},
+		}},
+		{"override a resource and code", Policy{Overrides: []Override{{Resource: "backup", Code: "resource-inactive", Severity: severity(Critical)}}}, []check{
+			{CodeResourceInactive, "backup", true, Critical},
+			{CodeResourceInactive, "report", true, Critical},
+			{CodeResourceInactive, "ping", true, Warning},
+		}},
+		{"override a parent", Policy{Overrides: []Override{{Resource: "ping-clone", Severity: severity(Info)}}}, []check{
+			{CodeResourceInactive, "ping", true, Info},
+		}},
+		{"override a node", Policy{Overrides: []Override{{Node: "node3", Severity: severity(Warning)}}}, []check{
+			{CodeNodeOffline, "node3", true, Warning},
+			{CodeNodeStandby, "node2", true, Critical},
+		}},
+		{"last override wins", Policy{Overrides: []Override{
+			{Code: "node-*", Severity: severity(Info)},
+			{Node: "node2", Severity: severity(Warning)},
+		}}, []check{
+			{CodeNodeOffline, "node3", true, Info},
+			{CodeNodeStandby, "node2", true, Warning},
+		}},
+		{"override without severity", Policy{Overrides: []Override{{Code: "node-offline"}}}, []check{
+			{CodeNodeOffline, "node3", true, Critical},
+		}},
+		{"require active", Policy{RequireActive: []string{"vip", "backup", "missing"}}, []check{
+			{CodeResourceRequired, "vip", false, 0},
+			{CodeResourceRequired, "backup", true, Critical},
+			{CodeResourceRequired, "missing", true, Critical},
+		}},
+		{"require an ignored resource", Policy{Ignore: []string{"backup"}, RequireActive: []string{"backup"}}, []check{
+			{CodeResourceInactive, "backup", false, 0},
+			{CodeResourceRequired, "backup", true, Critical},
+		}},
+		{"require a clone", Policy{RequireActive: []string{"ping-clone"}}, []check{
+			{CodeResourceRequired, "ping-clone", false, 0},
+		}},
+		{"min instances", Policy{MinInstances: []MinInstances{{Clone: "ping-clone", Count: 2}}}, []check{
+			{CodeCloneInstances, "ping-clone", true, Critical},
+		}},
+		{"min instances met", Policy{MinInstances: []MinInstances{{Clone: "ping-clone", Count: 1}}}, []check{
+			{CodeCloneInstances, "ping-clone", false, 0},
+		}},
+		{"min instances of a group", Policy{MinInstances: []MinInstances{{Clone: "dwgrp", Count: 1}}}, []check{
+			{CodeCloneInstances, "dwgrp", true, Critical},
+		}},
+	}
+
+	cs := parseFixture(t, "degraded.xml")
+	for _, tt := range tests {
+		r := tt.policy.Evaluate(cs)
+		for _, c := range tt.checks {
+			s, ok := severityOf(r, c.code, c.resource)
+			switch {
+			case ok != c.present:
+				t.Errorf("%v: finding %v about %v present = %v, want %v", tt.name, c.code, c.resource, ok, c.present)
+			case ok && s != c.severity:
+				t.Errorf("%v: finding %v about %v has severity %v, want %v", tt.name, c.code, c.resource, s, c.severity)
+			}
+		}
+	}
+}
+
+func TestPolicyApply(t *testing.T) {
+	cs := parseFixture(t, "healthy.xml")
+
+	var report Report
+	report.Add(Critical, CodeDCBusy, "node1", "", "the DC node1 is not idle")
+	report.Add(Critical, CodeStonithDisabled, "", "", "STONITH is disabled")
+	report.Add(Warning, CodeFailedAction, "node1", "app", "action app_monitor_10000 failed on node1")
+
+	p := Policy{
+		Ignore:        []string{"dwgrp"},
+		Overrides:     []Override{{Code: CodeStonithDisabled, Severity: severity(Warning)}},
+		RequireActive: []string{"missing"},
+	}
+
+	want := []finding{
+		{Critical, CodeDCBusy, "node1", ""},
+		{Warning, CodeStonithDisabled, "", ""},
+		{Critical, CodeResourceRequired, "", "missing"},
+	}
+	if got := findings(p.Apply(cs, report)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestPolicyFilterAndRequirements(t *testing.T) {
+	cs := parseFixture(t, "degraded.xml")
+
+	var report Report
+	report.Add(Critical, CodeResourceInactive, "", "report", "resource report is not running")
+	report.Add(Warning, CodeResourceInactive, "", "ping", "an instance of ping-clone (ping) is not running")
+
+	p := Policy{
+		Ignore:        []string{"report"},
+		Overrides:     []Override{{Resource: "ping-clone", Severity: severity(Critical)}},
+		RequireActive: []string{"report"},
+		MinInstances:  []MinInstances{{Clone: "ping-clone", Count: 2}},
+	}
+
+	want := []finding{{Critical, CodeResourceInactive, "", "ping"}}
+	if got := findings(p.Filter(cs, report)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %v, want %v", got, want)
+	}
+
+	want = []finding{
+		{Critical, CodeResourceRequired, "", "report"},
+		{Critical, CodeCloneInstances, "", "ping-clone"},
+	}
+	if got := findings(p.Requirements(cs)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Requirements() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+// Package health evaluates the health of a pacemaker cluster. Instead of stopping at the first problem
+// every check is run and the problems are returned as a `Report` of findings, each with a severity.
+// Only critical findings block a failover.
+package health
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors matched by the error returned from `Report.Err` with `errors.Is`.
+var (
+	// ErrUnhealthyNode is matched when a node has a critical finding.
+	ErrUnhealthyNode = errors.New("node is in an unhealthy state")
+	// ErrUnhealthyResource is matched when a resource has a critical finding.
+	ErrUnhealthyResource = errors.New("resource is not in a healthy state")
+	// ErrUnhealthyCluster is matched when the cluster itself has a critical finding, e.g. it lost quorum.
+	ErrUnhealthyCluster = errors.New("cluster is not in a state allowing a failover")
+	// ErrMigrationThreshold is matched when a resource reached, or is about to reach, its migration threshold on a node.
+	ErrMigrationThreshold = errors.New("resource is at its migration threshold")
+)
+
+// Severity is the severity of a finding.
+type Severity int
+
+// Severities of findings, from least to most severe.
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	}
+
+	return "unknown"
+}
+
+// MarshalText returns the name of the severity.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses the name of a severity so it can be read from the configuration file.
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = severity
+	return nil
+}
+
+// ParseSeverity returns the severity for its name, e.g. `critical`.
+func ParseSeverity(name string) (Severity, error) {
+	for _, s := range []Severity{Info, Warning, Critical} {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+
+	return Info, fmt.Errorf("unknown severity %q, expected info, warning or critical", name)
+}
+
+// Codes identifying the kind of a finding.
+const (
+	CodeClusterNoQuorum    = "cluster-no-quorum"
+	CodeClusterNoDC        = "cluster-no-dc"
+	CodeClusterMaintenance = "cluster-maintenance"
+	CodeClusterStopAll     = "cluster-stop-all"
+	CodeStonithDisabled    = "stonith-disabled"
+	CodeDCBusy             = "dc-busy"
+	CodeNodeOffline        = "node-offline"
+	CodeNodeStandby        = "node-standby"
+	CodeNodeMaintenance    = "node-maintenance"
+	CodeNodePending        = "node-pending"
+	CodeNodeUnclean        = "node-unclean"
+	CodeNodeShutdown       = "node-shutdown"
+	CodeResourceInactive   = "resource-inactive"
+	CodeResourceBlocked    = "resource-blocked"
+	CodeResourceFailed     = "resource-failed"
+	CodeResourceUnmanaged  = "resource-unmanaged"
+	CodeResourceOrphaned   = "resource-orphaned"
+	CodeFailedAction       = "failed-action"
+	CodeMigrationThreshold = "migration-threshold"
+)
+
+// Finding is a problem found in the cluster.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Code identifies the kind of the finding, see the `Code*` constants.
+	Code string `json:"code"`
+	// Node and Resource are the offending node and resource, if any.
+	Node     string `json:"node,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// Message is a human readable explanation of the finding.
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%v] %v", strings.ToUpper(f.Severity.String()), f.Message)
+}
+
+// Report contains the findings of a health evaluation.
+type Report struct {
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Add adds a finding to the report.
+func (r *Report) Add(severity Severity, code, node, resource, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{
+		Severity: severity,
+		Code:     code,
+		Node:     node,
+		Resource: resource,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Merge adds the findings of the other report to the report.
+func (r *Report) Merge(other Report) {
+	r.Findings = append(r.Findings, other.Findings...)
+}
+
+// Critical returns true if the report contains a critical finding.
+func (r Report) Critical() bool {
+	return len(r.WithSeverity(Critical)) > 0
+}
+
+// WithSeverity returns the findings of the severity.
+func (r Report) WithSeverity(s Severity) []Finding {
+	var findings []Finding
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// Err returns nil if the report has no critical findings or an error listing them otherwise. The error
+// matches `ErrUnhealthyCluster`, `ErrUnhealthyNode`, `ErrUnhealthyResource` and `ErrMigrationThreshold`
+// depending on the findings.
+func (r Report) Err() error {
+	if !r.Critical() {
+		return nil
+	}
+
+	return &ReportError{Report: r}
+}
+
+func (r Report) String() string {
+	if len(r.Findings) == 0 {
+		return "No problems found.\n"
+	}
+
+	var str string
+	for _, s := range []Severity{Critical, Warning, Info} {
+		for _, f := range r.WithSeverity(s) {
+			str += f.String() + "\n"
+		}
+	}
+
+	return str
+}
+
+// ReportError is returned by `Report.Err` when the report contains critical findings.
+type ReportError struct {
+	Report Report
+}
+
+func (e *ReportError) Error() string {
+	var messages []string
+	for _, f := range e.Report.WithSeverity(Critical) {
+		messages = append(messages, f.Message)
+	}
+
+	return "the cluster is not healthy: " + strings.Join(messages, "; ")
+}
+
+// Is matches the sentinel errors of the kinds of critical findings in the report.
+func (e *ReportError) Is(target error) bool {
+	for _, f := range e.Report.WithSeverity(Critical) {
+		switch {
+		case target == ErrMigrationThreshold && f.Code == CodeMigrationThreshold:
+			return true
+		case target == ErrUnhealthyCluster && f.Resource == "" && !strings.HasPrefix(f.Code, "node-"):
+			return true
+		case target == ErrUnhealthyNode && strings.HasPrefix(f.Code, "node-"):
+			return true
+		case target == ErrUnhealthyResource && f.Resource != "" && f.Code != CodeMigrationThreshold:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// freeValue is printed by `crm_attribute --query` when the cluster property does not exist.
+const freeValue = "none"
+
+// holder is the value of the cluster property: the owner of the lock and its expiry, e.g. `node1,2026-10-17T04:30:00Z`.
+type holder struct {
+	Owner  string
+	Expiry time.Time
+}
+
+func (h holder) String() string {
+	return h.Owner + "," + h.Expiry.UTC().Format(time.RFC3339)
+}
+
+// parseHolder parses the value of the cluster property. A free or invalid value returns an empty holder.
+func parseHolder(value string) holder {
+	parts := strings.SplitN(strings.TrimSpace(value), ",", 2)
+	if len(parts) != 2 {
+		return holder{}
+	}
+
+	expiry, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return holder{}
+	}
+
+	return holder{Owner: parts[0], Expiry: expiry}
+}
+
+// acquireAttribute takes the lock stored in the cluster property unless another node holds it and it has not expired.
+// Pacemaker has no compare-and-swap: two nodes may both find the lock free and write it. The CIB serializes the
+// writes, so after waiting for `Settle` both nodes read the same owner and only that node proceeds.
+func (l *Locker) acquireAttribute(ctx context.Context) (func(), error) {
+	owner, err := l.owner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := l.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A lock held by this node was left by a run that crashed, the local file lock guarantees it is not running anymore.
+	now := l.now()
+	if held.Owner != "" && held.Owner != owner && now.Before(held.Expiry) {
+		return nil, fmt.Errorf("%w: the lock %v is held by %v until %v", ErrNotLeader, l.name(), held.Owner, held.Expiry.Format(time.RFC3339))
+	}
+
+	ttl := l.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	mine := holder{Owner: owner, Expiry: now.Add(ttl)}
+	if _, err := l.Executor.Run(ctx, l.command("--update", mine.String())); err != nil {
+		return nil, err
+	}
+
+	settle := l.Settle
+	if settle <= 0 {
+		settle = DefaultSettle
+	}
+
+	timer := time.NewTimer(settle)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	held, err = l.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if held.Owner != owner {
+		return nil, fmt.Errorf("%w: the lock %v was taken by %v at the same time", ErrNotLeader, l.name(), held.Owner)
+	}
+
+	return func() { l.releaseAttribute(owner) }, nil
+}
+
+// releaseAttribute deletes the cluster property if this node still holds it.
+func (l *Locker) releaseAttribute(owner string) {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	held, err := l.query(ctx)
+	if err != nil {
+		l.logf("Unable to release the lock %v, it expires with its ttl: %v", l.name(), err)
+		return
+	}
+
+	if held.Owner != owner {
+		return
+	}
+
+	if _, err := l.Executor.Run(ctx, l.command("--delete")); err != nil {
+		l.logf("Unable to release the lock %v, it expires with its ttl: %v", l.name(), err)
+	}
+}
+
+// query returns the holder of the cluster property.
+func (l *Locker) query(ctx context.Context) (holder, error) {
+	res, err := l.Executor.Run(ctx, l.command("--query", "--quiet", "--default", freeValue))
+	if err != nil {
+		return holder{}, err
+	}
+
+	return parseHolder(res.Stdout), nil
+}
+
+// command returns the `crm_attribute` command for the cluster property with the arguments.
+func (l *Locker) command(args ...string) runner.Command {
+	return runner.NewCommand(append([]string{"crm_attribute", "--type", "crm_config", "--name", l.name()}, args...)...)
+}
+
+func (l *Locker) name() string {
+	if l.Name == "" {
+		return DefaultName(l.Profile)
+	}
+
+	return l.Name
+}
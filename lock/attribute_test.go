@@ -0,0 +1,162 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// now is the time of the runs in the tests.
+var now = time.Date(2026, 10, 17, 4, 30, 0, 0, time.UTC)
+
+// fakeProperty is a cluster property read and written with `crm_attribute`.
+type fakeProperty struct {
+	mu    sync.Mutex
+	name  string
+	value string
+	// overwrite is written by another node right after this node updates the property, if set.
+	overwrite string
+	// fail makes every `crm_attribute` command fail.
+	fail bool
+}
+
+func (p *fakeProperty) run(ctx context.Context, cmd runner.Command) (runner.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := runner.Result{Command: cmd}
+	prefix := []string{"crm_attribute", "--type", "crm_config", "--name", p.name}
+	if p.fail || len(cmd.Args) <= len(prefix) || !reflect.DeepEqual(cmd.Args[:len(prefix)], prefix) {
+		res.ExitCode = 1
+		return res, &runner.Error{Result: res, Err: errors.New("exit status 1")}
+	}
+
+	switch args := cmd.Args[len(prefix):]; args[0] {
+	case "--query":
+		res.Stdout = freeValue + "\n"
+		if p.value != "" {
+			res.Stdout = p.value + "\n"
+		}
+	case "--update":
+		p.value = args[1]
+		if p.overwrite != "" {
+			p.value = p.overwrite
+		}
+	case "--delete":
+		p.value = ""
+	}
+
+	return res, nil
+}
+
+// ops returns the `crm_attribute` operations that were run.
+func ops(fake *runner.Fake) []string {
+	var ops []string
+	for _, cmd := range fake.Calls() {
+		if cmd.Args[0] == "crm_attribute" && len(cmd.Args) > 5 {
+			ops = append(ops, strings.TrimPrefix(cmd.Args[5], "--"))
+		}
+	}
+	return ops
+}
+
+func TestAcquireAttribute(t *testing.T) {
+	mine := holder{Owner: "node1", Expiry: now.Add(DefaultTTL)}.String()
+
+	tests := []struct {
+		name      string
+		held      string
+		overwrite string
+		wantErr   error
+		ops       []string
+		// after is the value of the property after the lock is released, or was not acquired.
+		after string
+	}{
+		{
+			name: "free",
+			ops:  []string{"query", "update", "query", "query", "delete"},
+		},
+		{
+			name:    "held by another node",
+			held:    "node2,2026-10-17T05:00:00Z",
+			wantErr: ErrNotLeader,
+			ops:     []string{"query"},
+			after:   "node2,2026-10-17T05:00:00Z",
+		},
+		{
+			name: "expired",
+			held: "node2,2026-10-17T04:00:00Z",
+			ops:  []string{"query", "update", "query", "query", "delete"},
+		},
+		{
+			name: "left by a crashed run of this node",
+			held: "node1,2026-10-17T05:00:00Z",
+			ops:  []string{"query", "update", "query", "query", "delete"},
+		},
+		{
+			name: "invalid value",
+			held: "garbage",
+			ops:  []string{"query", "update", "query", "query", "delete"},
+		},
+		{
+			name:      "taken by another node at the same time",
+			overwrite: "node2,2026-10-17T05:30:00Z",
+			wantErr:   ErrNotLeader,
+			ops:       []string{"query", "update", "query"},
+			after:     "node2,2026-10-17T05:30:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prop := &fakeProperty{name: "gofailover-lock-pkm", value: tt.held, overwrite: tt.overwrite}
+			fake := &runner.Fake{Handler: prop.run}
+			l := &Locker{
+				Config:   Config{Strategy: StrategyAttribute},
+				Profile:  "pkm",
+				Executor: fake,
+				Owner:    "node1",
+				Settle:   time.Millisecond,
+				Now:      func() time.Time { return now },
+			}
+
+			release, err := l.acquireAttribute(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("acquireAttribute() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil {
+				if prop.value != mine {
+					t.Errorf("acquireAttribute() wrote %q, want %q", prop.value, mine)
+				}
+				release()
+			} else if release != nil {
+				t.Errorf("acquireAttribute() returned a release function with an error")
+			}
+
+			if got := ops(fake); !reflect.DeepEqual(got, tt.ops) {
+				t.Errorf("acquireAttribute() ran %v, want %v", got, tt.ops)
+			}
+
+			if prop.value != tt.after {
+				t.Errorf("the property is %q after the run, want %q", prop.value, tt.after)
+			}
+		})
+	}
+}
+
+func TestAcquireAttributeFailure(t *testing.T) {
+	fake := &runner.Fake{Handler: (&fakeProperty{name: "gofailover-lock-pkm", fail: true}).run}
+	l := &Locker{Profile: "pkm", Executor: fake, Owner: "node1", Settle: time.Millisecond}
+
+	_, err := l.acquireAttribute(context.Background())
+	if err == nil || errors.Is(err, ErrNotLeader) {
+		t.Errorf("acquireAttribute() error = %v, want an error other than %v", err, ErrNotLeader)
+	}
+}
@@ -0,0 +1,238 @@
+// Package lock elects the node performing a run. The tool is installed on every node of a cluster so it still runs
+// after a failover, but only one node may run the failover commands. A run first takes a local file lock, so two runs
+// never overlap on a node, then the cluster-wide lock of its `Strategy`. If the cluster-wide lock cannot be checked,
+// e.g. because the cluster tools fail, the local file lock alone is used.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// ErrNotLeader is returned when another node, or another run on this node, holds the lock.
+var ErrNotLeader = errors.New("not leader, skipping")
+
+// Strategies of the cluster-wide lock.
+const (
+	// StrategyDC only lets the node that is the DC of the cluster run.
+	StrategyDC = "dc"
+	// StrategyAttribute stores the owner and the expiry of the lock in a cluster property with `crm_attribute`.
+	StrategyAttribute = "attribute"
+	// StrategyFile only takes the local file lock.
+	StrategyFile = "file"
+)
+
+// Defaults of the `Config` settings. The default file and cluster property are named after the profile, see
+// `DefaultFile` and `DefaultName`.
+const (
+	DefaultStrategy = StrategyDC
+	DefaultTTL      = time.Hour
+)
+
+// DefaultFile returns the local file lock of the profile when `Config.File` is not set, e.g. `/var/lock/gofailover-pkm.lock`.
+func DefaultFile(profile string) string {
+	if profile == "" {
+		return "/var/lock/gofailover.lock"
+	}
+
+	return "/var/lock/gofailover-" + profile + ".lock"
+}
+
+// DefaultName returns the cluster property of the profile when `Config.Name` is not set, e.g. `gofailover-lock-pkm`.
+func DefaultName(profile string) string {
+	if profile == "" {
+		return "gofailover-lock"
+	}
+
+	return "gofailover-lock-" + profile
+}
+
+// DefaultSettle is used when `Locker.Settle` is not set.
+const DefaultSettle = 2 * time.Second
+
+// releaseTimeout bounds the release of the cluster-wide lock, which also happens after a run was interrupted.
+const releaseTimeout = 30 * time.Second
+
+// Config is the `lock` section of the configuration file.
+//
+// Example config:
+//
+//	lock:
+//	  strategy: attribute
+//	  ttl: 1h
+type Config struct {
+	// Strategy is `dc`, `attribute` or `file`, `DefaultStrategy` if it is empty.
+	Strategy string `mapstructure:"strategy"`
+	// File is the path of the local file lock, `DefaultFile` of the profile if it is empty. A file set here is shared
+	// by every profile, their runs then never overlap.
+	File string `mapstructure:"file"`
+	// Name is the name of the cluster property of the `attribute` strategy, `DefaultName` of the profile if it is
+	// empty. As with `File`, a name set here is shared by every profile.
+	Name string `mapstructure:"name"`
+	// TTL is how long the cluster property is valid. It must be longer than the longest run, a node that
+	// crashed during a run blocks the other nodes until then.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// Validate returns an error if the strategy is unknown.
+func (c Config) Validate() error {
+	switch c.Strategy {
+	case "", StrategyDC, StrategyAttribute, StrategyFile:
+	default:
+		return fmt.Errorf("unknown lock strategy %q, expected %v, %v or %v", c.Strategy, StrategyDC, StrategyAttribute, StrategyFile)
+	}
+
+	if c.TTL < 0 {
+		return fmt.Errorf("invalid lock ttl %v", c.TTL)
+	}
+
+	return nil
+}
+
+// Locker acquires the lock of a run.
+type Locker struct {
+	Config
+	// Profile is the name of the profile of the run. Each profile has its own lock by default, so the runs of
+	// profiles scheduled at the same time do not make each other exit as not leader.
+	Profile string
+	// Executor runs `crm_node` and `crm_attribute`.
+	Executor runner.Executor
+	// Status returns the current status of the cluster, used to find the DC.
+	Status func(ctx context.Context) (crm.ClusterStatus, error)
+	// Owner identifies this node in the cluster property. The node name of `crm_node -n`, or the host name, is used if it is empty.
+	Owner string
+	// Settle is how long to wait after writing the cluster property before checking that no other node overwrote it.
+	Settle time.Duration
+	// Now returns the current time, `time.Now` if it is nil.
+	Now func() time.Time
+	// Logf logs the fallback to the local file lock.
+	Logf func(format string, args ...interface{})
+}
+
+// Acquire takes the local file lock and the cluster-wide lock. An error wrapping `ErrNotLeader` is returned if another
+// node or run holds the lock. The returned function releases the locks.
+func (l *Locker) Acquire(ctx context.Context) (func(), error) {
+	path := l.File
+	if path == "" {
+		path = DefaultFile(l.Profile)
+	}
+
+	file, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := l.acquireCluster(ctx)
+	switch {
+	case err != nil && (errors.Is(err, ErrNotLeader) || ctx.Err() != nil):
+		file.Close()
+		return nil, err
+	case err != nil:
+		l.logf("Unable to check the %v lock, falling back to the local file lock %v: %v", l.strategy(), path, err)
+	}
+
+	return func() {
+		if release != nil {
+			release()
+		}
+		file.Close()
+	}, nil
+}
+
+// acquireCluster takes the cluster-wide lock of the strategy. The returned function, if any, releases it.
+func (l *Locker) acquireCluster(ctx context.Context) (func(), error) {
+	switch l.strategy() {
+	case StrategyDC:
+		return nil, l.checkDC(ctx)
+	case StrategyAttribute:
+		return l.acquireAttribute(ctx)
+	}
+
+	return nil, nil
+}
+
+// checkDC returns an error wrapping `ErrNotLeader` if this node is not the DC. The DC does not change during
+// a failover, moving resources does not move the DC.
+func (l *Locker) checkDC(ctx context.Context) error {
+	if l.Status == nil {
+		return errors.New("the cluster status is not available")
+	}
+
+	cs, err := l.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	dc := cs.Status.DesignatedController.Node
+	if dc == "" {
+		return errors.New("the cluster has no DC")
+	}
+
+	node, err := l.localNode(ctx)
+	if err != nil {
+		return err
+	}
+
+	if node != dc {
+		return fmt.Errorf("%w: %v is the DC, this node is %v", ErrNotLeader, dc, node)
+	}
+
+	return nil
+}
+
+// localNode returns the name of this node in the cluster.
+func (l *Locker) localNode(ctx context.Context) (string, error) {
+	res, err := l.Executor.Run(ctx, runner.NewCommand("crm_node", "-n"))
+	if err != nil {
+		return "", err
+	}
+
+	node := strings.TrimSpace(res.Stdout)
+	if node == "" {
+		return "", errors.New("`crm_node -n` did not return the name of this node")
+	}
+
+	return node, nil
+}
+
+// owner returns the owner of the cluster property for this node.
+func (l *Locker) owner(ctx context.Context) (string, error) {
+	if l.Owner != "" {
+		return l.Owner, nil
+	}
+
+	if node, err := l.localNode(ctx); err == nil {
+		return node, nil
+	}
+
+	return os.Hostname()
+}
+
+func (l *Locker) strategy() string {
+	if l.Strategy == "" {
+		return DefaultStrategy
+	}
+
+	return l.Strategy
+}
+
+func (l *Locker) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+
+	return time.Now()
+}
+
+func (l *Locker) logf(format string, args ...interface{}) {
+	if l.Logf != nil {
+		l.Logf(format, args...)
+	}
+}
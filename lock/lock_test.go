@@ -0,0 +1,128 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/KalebHawkins/gofailover/crm"
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// clusterWithDC returns a status function reporting `dc` as the DC of the cluster.
+func clusterWithDC(dc string) func(ctx context.Context) (crm.ClusterStatus, error) {
+	return func(ctx context.Context) (crm.ClusterStatus, error) {
+		var cs crm.ClusterStatus
+		cs.Status.DesignatedController.Node = dc
+		return cs, nil
+	}
+}
+
+// localNode returns a `runner.Fake` answering `crm_node -n` with the node name.
+func localNode(node string) *runner.Fake {
+	return &runner.Fake{Results: map[string]runner.Result{
+		"crm_node -n": {Stdout: node + "\n"},
+	}}
+}
+
+func TestCheckDC(t *testing.T) {
+	errAny := errors.New("any error")
+	tests := []struct {
+		name     string
+		status   func(ctx context.Context) (crm.ClusterStatus, error)
+		executor *runner.Fake
+		// wantErr is checked with `errors.Is`, `errAny` expects an error other than `ErrNotLeader`.
+		wantErr error
+	}{
+		{"this node is the DC", clusterWithDC("node1"), localNode("node1"), nil},
+		{"another node is the DC", clusterWithDC("node2"), localNode("node1"), ErrNotLeader},
+		{"no DC", clusterWithDC(""), localNode("node1"), errAny},
+		{"no status", nil, localNode("node1"), errAny},
+		{
+			"status failed",
+			func(ctx context.Context) (crm.ClusterStatus, error) {
+				return crm.ClusterStatus{}, errors.New("crm_mon failed")
+			},
+			localNode("node1"),
+			errAny,
+		},
+		{"crm_node failed", clusterWithDC("node1"), &runner.Fake{}, errAny},
+		{"crm_node printed nothing", clusterWithDC("node1"), localNode(""), errAny},
+	}
+
+	for _, tt := range tests {
+		l := &Locker{Executor: tt.executor, Status: tt.status}
+		err := l.checkDC(context.Background())
+
+		switch {
+		case tt.wantErr == errAny:
+			if err == nil || errors.Is(err, ErrNotLeader) {
+				t.Errorf("%v: checkDC() error = %v, want an error other than %v", tt.name, err, ErrNotLeader)
+			}
+		case !errors.Is(err, tt.wantErr):
+			t.Errorf("%v: checkDC() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	tests := []struct {
+		profile string
+		file    string
+		name    string
+	}{
+		{"", "/var/lock/gofailover.lock", "gofailover-lock"},
+		{"pkm", "/var/lock/gofailover-pkm.lock", "gofailover-lock-pkm"},
+		{"dw", "/var/lock/gofailover-dw.lock", "gofailover-lock-dw"},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultFile(tt.profile); got != tt.file {
+			t.Errorf("DefaultFile(%q) = %v, want %v", tt.profile, got, tt.file)
+		}
+		if got := DefaultName(tt.profile); got != tt.name {
+			t.Errorf("DefaultName(%q) = %v, want %v", tt.profile, got, tt.name)
+		}
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+	locker := func(file, dc string) *Locker {
+		return &Locker{
+			Config:   Config{File: filepath.Join(dir, file)},
+			Executor: localNode("node1"),
+			Status:   clusterWithDC(dc),
+		}
+	}
+
+	release, err := locker("pkm.lock", "node1").Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// Another run on this node is not leader, a run of another profile is.
+	if _, err := locker("pkm.lock", "node1").Acquire(context.Background()); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Acquire() of a held file error = %v, want %v", err, ErrNotLeader)
+	}
+	other, err := locker("dw.lock", "node1").Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() of another file error = %v", err)
+	}
+	other()
+
+	release()
+
+	// The file lock is released when this node is not the DC.
+	if _, err := locker("pkm.lock", "node2").Acquire(context.Background()); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Acquire() on another node than the DC error = %v, want %v", err, ErrNotLeader)
+	}
+
+	// The local file lock is used alone when the DC cannot be checked.
+	release, err = locker("pkm.lock", "").Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() without a DC error = %v, want the local file lock", err)
+	}
+	release()
+}
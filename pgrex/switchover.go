@@ -0,0 +1,282 @@
+// Package pgrex drives `pg-rex_switchover`, the switchover tool of PG-REX PostgreSQL clusters.
+// Instead of answering yes to everything like `yes | pg-rex_switchover`, every prompt of the tool is
+// recognised and only the expected ones are answered. The run is aborted on any other prompt or on
+// a warning, and the output is parsed into a `Result` with the old and new primary nodes.
+package pgrex
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Errors returned by `Switchover.Run`. They are wrapped with the offending output.
+var (
+	// ErrUnexpectedPrompt is returned when the tool asks a question that is not one of the expected prompts.
+	ErrUnexpectedPrompt = errors.New("unexpected prompt from pg-rex_switchover")
+	// ErrWarning is returned when the tool prints a warning or an error.
+	ErrWarning = errors.New("pg-rex_switchover reported a problem")
+	// ErrNotCompleted is returned when the tool exits without reporting a completed switchover.
+	ErrNotCompleted = errors.New("pg-rex_switchover did not report a completed switchover")
+)
+
+// Default patterns matching the output of `pg-rex_switchover`. They can be replaced in the configuration
+// file to match the version and language of the installed PG-REX tools.
+const (
+	DefaultPromptPattern     = `(?i)(\(y/n\)|\[y/n\]|\(yes/no\)|\[yes/no\])\s*:?\s*$`
+	DefaultWarningPattern    = `(?i)\b(warning|error|fatal)\b|警告|エラー`
+	DefaultCompletedPattern  = `(?i)switchover\s+(is\s+|has\s+been\s+)?(completed|succeeded|finished)|スイッチオーバー.*(完了|成功)`
+	DefaultOldPrimaryPattern = `(?i)(?:old|current)\s+primary[^:]*:\s*(\S+)`
+	DefaultNewPrimaryPattern = `(?i)new\s+primary[^:]*:\s*(\S+)`
+)
+
+// DefaultPrompts are the prompts answered when `Switchover.Prompts` is empty: the confirmation of the switchover.
+var DefaultPrompts = []Prompt{
+	{Pattern: `(?i)switch\s*over|スイッチオーバー`, Answer: "y"},
+}
+
+// Prompt is an expected question of the tool and its answer. Each prompt is answered at most once.
+type Prompt struct {
+	// Pattern is a regular expression matched against the line of the question.
+	Pattern string `mapstructure:"pattern"`
+	// Answer is written, followed by a new line, to the standard input of the tool.
+	Answer string `mapstructure:"answer"`
+}
+
+// Switchover runs `pg-rex_switchover` and answers its prompts. Every field is optional.
+//
+// Example config:
+//
+//	switchover:
+//	  enabled: true
+//	  prompts:
+//	    - pattern: "(?i)execute switchover"
+//	      answer: y
+//	  warningPattern: "(?i)^\\s*warning"
+type Switchover struct {
+	// Enabled runs the switchover as part of the failover of a profile.
+	Enabled bool `mapstructure:"enabled"`
+	// Command is the switchover command, `pg-rex_switchover` by default.
+	Command runner.Command `mapstructure:"command"`
+	// Prompts are the expected prompts, `DefaultPrompts` by default.
+	Prompts []Prompt `mapstructure:"prompts"`
+	// PromptPattern recognises a question waiting for an answer at the end of the output.
+	PromptPattern string `mapstructure:"promptPattern"`
+	// WarningPattern recognises the lines, including prompts, that abort the switchover.
+	WarningPattern string `mapstructure:"warningPattern"`
+	// CompletedPattern recognises the line reporting a completed switchover.
+	CompletedPattern string `mapstructure:"completedPattern"`
+	// OldPrimaryPattern and NewPrimaryPattern capture the name of the old and new primary nodes in their first group.
+	OldPrimaryPattern string `mapstructure:"oldPrimaryPattern"`
+	NewPrimaryPattern string `mapstructure:"newPrimaryPattern"`
+}
+
+// Result is the outcome of a switchover.
+type Result struct {
+	// OldPrimary and NewPrimary are the primary nodes reported by the tool. They are empty if the tool
+	// did not report them.
+	OldPrimary string
+	NewPrimary string
+	// Answered contains the prompts that were answered, in order.
+	Answered []string
+	// Command is the result of the command, including its output.
+	Command runner.Result
+}
+
+// Validate returns an error if a pattern is not a valid regular expression.
+func (s Switchover) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+// Run runs the switchover. An error wrapping `ErrUnexpectedPrompt` or `ErrWarning` is returned, after the
+// tool has been killed, if the tool asks an unexpected question or prints a warning. An error wrapping
+// `ErrNotCompleted` is returned if the tool exits without reporting a completed switchover.
+func (s Switchover) Run(ctx context.Context, executor runner.Executor) (Result, error) {
+	var res Result
+
+	d, err := s.compile()
+	if err != nil {
+		return res, err
+	}
+	d.result = &res
+
+	cmd := s.Command
+	if len(cmd.Args) == 0 {
+		cmd = runner.NewCommand("pg-rex_switchover")
+	}
+	cmd.Stdin, cmd.RepeatStdin = "", false
+	cmd.Stream = true
+	cmd.Dialog = d.run
+
+	res.Command, err = executor.Run(ctx, cmd)
+	if err != nil {
+		return res, err
+	}
+
+	if !d.completed {
+		return res, fmt.Errorf("%w: %v", ErrNotCompleted, lastLine(res.Command.Stdout))
+	}
+
+	return res, nil
+}
+
+// compile returns a dialog with the patterns of the switchover, using the defaults for the empty ones.
+func (s Switchover) compile() (*dialog, error) {
+	d := &dialog{}
+
+	patterns := []struct {
+		re           **regexp.Regexp
+		value, deflt string
+		name         string
+	}{
+		{&d.prompt, s.PromptPattern, DefaultPromptPattern, "promptPattern"},
+		{&d.warning, s.WarningPattern, DefaultWarningPattern, "warningPattern"},
+		{&d.completedRe, s.CompletedPattern, DefaultCompletedPattern, "completedPattern"},
+		{&d.oldPrimary, s.OldPrimaryPattern, DefaultOldPrimaryPattern, "oldPrimaryPattern"},
+		{&d.newPrimary, s.NewPrimaryPattern, DefaultNewPrimaryPattern, "newPrimaryPattern"},
+	}
+
+	for _, p := range patterns {
+		value := p.value
+		if value == "" {
+			value = p.deflt
+		}
+
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v: %v", p.name, err)
+		}
+		*p.re = re
+	}
+
+	prompts := s.Prompts
+	if len(prompts) == 0 {
+		prompts = DefaultPrompts
+	}
+
+	for _, p := range prompts {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt pattern %q: %v", p.Pattern, err)
+		}
+		d.expected = append(d.expected, expectedPrompt{re: re, answer: p.Answer})
+	}
+
+	return d, nil
+}
+
+// dialog answers the prompts of a running switchover, see `runner.Dialog`.
+type dialog struct {
+	prompt, warning, completedRe, oldPrimary, newPrimary *regexp.Regexp
+	expected                                             []expectedPrompt
+
+	result    *Result
+	completed bool
+}
+
+type expectedPrompt struct {
+	re       *regexp.Regexp
+	answer   string
+	answered bool
+}
+
+func (d *dialog) run(stdout io.Reader, stdin io.Writer) error {
+	r := bufio.NewReader(stdout)
+
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return d.line(string(line))
+		}
+		if err != nil {
+			return err
+		}
+
+		if b == '\n' {
+			if err := d.line(string(line)); err != nil {
+				return err
+			}
+			line = line[:0]
+			continue
+		}
+
+		// A question waits for its answer without ending the line.
+		line = append(line, b)
+		if d.prompt.Match(line) {
+			if err := d.answer(string(line), stdin); err != nil {
+				return err
+			}
+			line = line[:0]
+		}
+	}
+}
+
+// line checks a complete line of output for warnings and the result of the switchover.
+func (d *dialog) line(line string) error {
+	line = trimLine(line)
+	if d.warning.MatchString(line) {
+		return fmt.Errorf("%w: %v", ErrWarning, line)
+	}
+
+	if m := d.oldPrimary.FindStringSubmatch(line); len(m) > 1 {
+		d.result.OldPrimary = m[1]
+	}
+	if m := d.newPrimary.FindStringSubmatch(line); len(m) > 1 {
+		d.result.NewPrimary = m[1]
+	}
+	if d.completedRe.MatchString(line) {
+		d.completed = true
+	}
+
+	return nil
+}
+
+// answer answers the prompt if it is expected and has not been answered yet.
+func (d *dialog) answer(prompt string, stdin io.Writer) error {
+	prompt = trimLine(prompt)
+	if d.warning.MatchString(prompt) {
+		return fmt.Errorf("%w: %v", ErrWarning, prompt)
+	}
+
+	for i := range d.expected {
+		p := &d.expected[i]
+		if p.answered || !p.re.MatchString(prompt) {
+			continue
+		}
+
+		if _, err := io.WriteString(stdin, p.answer+"\n"); err != nil {
+			return fmt.Errorf("failed to answer %q: %v", prompt, err)
+		}
+		p.answered = true
+		d.result.Answered = append(d.result.Answered, prompt)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnexpectedPrompt, prompt)
+}
+
+// trimLine trims the spaces around a line. The rest of an answered prompt, e.g. the `: ` following `(y/N)`,
+// is trimmed from the start of the next line as well.
+func trimLine(line string) string {
+	return strings.TrimSpace(strings.TrimLeft(line, ": \t"))
+}
+
+// lastLine returns the last non-empty line of the output.
+func lastLine(out string) string {
+	var last string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			last = line
+		}
+	}
+
+	return last
+}
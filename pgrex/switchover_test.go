@@ -0,0 +1,98 @@
+package pgrex
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// transcript returns a `runner.Fake` replaying the output of `pg-rex_switchover` recorded in testdata.
+func transcript(t *testing.T, name string) *runner.Fake {
+	t.Helper()
+
+	out, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &runner.Fake{Results: map[string]runner.Result{
+		"pg-rex_switchover": {Stdout: string(out)},
+	}}
+}
+
+func TestSwitchoverRun(t *testing.T) {
+	tests := []struct {
+		transcript string
+		wantErr    error
+		// stdin is what was written to the tool.
+		stdin      string
+		oldPrimary string
+		newPrimary string
+	}{
+		{
+			transcript: "completed.txt",
+			stdin:      "y\n",
+			oldPrimary: "node1",
+			newPrimary: "node2",
+		},
+		{
+			transcript: "warning.txt",
+			wantErr:    ErrWarning,
+			oldPrimary: "node1",
+			newPrimary: "node2",
+		},
+		{
+			transcript: "unexpected_prompt.txt",
+			wantErr:    ErrUnexpectedPrompt,
+			stdin:      "y\n",
+			oldPrimary: "node1",
+			newPrimary: "node2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transcript, func(t *testing.T) {
+			fake := transcript(t, tt.transcript)
+
+			res, err := Switchover{}.Run(context.Background(), fake)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Run() error = %v, want nil", err)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// The tool is killed when the switchover is aborted.
+			if tt.wantErr != nil && !errors.Is(err, runner.ErrCommandFailed) {
+				t.Errorf("Run() error = %v, want a failed command", err)
+			}
+
+			calls := fake.Calls()
+			if len(calls) != 1 {
+				t.Fatalf("Run() ran %v commands, want 1", len(calls))
+			}
+			if calls[0].Stdin != tt.stdin {
+				t.Errorf("Run() answered %q, want %q", calls[0].Stdin, tt.stdin)
+			}
+
+			if res.OldPrimary != tt.oldPrimary || res.NewPrimary != tt.newPrimary {
+				t.Errorf("Run() primary nodes = %v -> %v, want %v -> %v", res.OldPrimary, res.NewPrimary, tt.oldPrimary, tt.newPrimary)
+			}
+		})
+	}
+}
+
+func TestSwitchoverRunNotCompleted(t *testing.T) {
+	fake := &runner.Fake{Results: map[string]runner.Result{
+		"pg-rex_switchover": {Stdout: "Starting the switchover procedure.\nChecking the cluster status ...\n"},
+	}}
+
+	_, err := Switchover{}.Run(context.Background(), fake)
+	if !errors.Is(err, ErrNotCompleted) {
+		t.Errorf("Run() error = %v, want %v", err, ErrNotCompleted)
+	}
+}
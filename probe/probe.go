@@ -0,0 +1,282 @@
+// Package probe checks that an application actually works after a failover. Pacemaker may report a
+// resource as active while the application behind it is dead, so probes talk to the application the
+// way its clients do: over HTTP, TCP, SQL or with an arbitrary command. Each probe is retried until it
+// succeeds or runs out of attempts.
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Defaults used when a probe does not set them.
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultInterval = 5 * time.Second
+)
+
+// ErrProbeFailed is matched by the error returned from `Results.Err` with `errors.Is`.
+var ErrProbeFailed = errors.New("probe failed")
+
+// Probe is a check of the application. Exactly one of `HTTP`, `TCP`, `SQL` or `Command` must be set.
+//
+// Example config:
+//
+//	probes:
+//	  - name: web ui
+//	    http:
+//	      url: https://dw-vip:8443/health
+//	      status: 200
+//	      body: "OK"
+//	    retries: 5
+//	    interval: 10s
+//	    timeout: 5s
+//	  - tcp: dw-vip:5432
+//	  - sql:
+//	      connection: "host=pkm-vip user=postgres dbname=postgres"
+//	      query: "SELECT pg_is_in_recovery()"
+//	      expect: "^f$"
+//	  - command: systemctl is-active dwapp
+type Probe struct {
+	// Name is used in the results, the target of the probe by default.
+	Name string `mapstructure:"name"`
+
+	HTTP    HTTP           `mapstructure:"http"`
+	TCP     string         `mapstructure:"tcp"`
+	SQL     SQL            `mapstructure:"sql"`
+	Command runner.Command `mapstructure:"command"`
+	// Expect is a regular expression the output of the command must match.
+	Expect string `mapstructure:"expect"`
+
+	// Retries is the number of attempts made after the first one fails.
+	Retries int `mapstructure:"retries"`
+	// Interval is the delay between attempts, `DefaultInterval` by default.
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout bounds each attempt, `DefaultTimeout` by default.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// HTTP is a `GET` request that must return the expected status code and body.
+type HTTP struct {
+	URL string `mapstructure:"url"`
+	// Status is the expected status code, 200 by default.
+	Status int `mapstructure:"status"`
+	// Body is a regular expression the body must match.
+	Body string `mapstructure:"body"`
+	// Insecure skips the verification of the server's certificate, e.g. for self-signed certificates.
+	Insecure bool `mapstructure:"insecure"`
+}
+
+// SQL is a query run with `psql` that must succeed and whose output must match `Expect`.
+type SQL struct {
+	// Connection is the libpq connection string passed to `psql`.
+	Connection string `mapstructure:"connection"`
+	// Query is `SELECT 1` by default.
+	Query string `mapstructure:"query"`
+	// Expect is a regular expression the output of the query must match.
+	Expect string `mapstructure:"expect"`
+	// Command is the `psql` command, `psql` by default.
+	Command runner.Command `mapstructure:"command"`
+}
+
+// Validate returns an error unless exactly one kind of probe is set and its settings are valid.
+func (p Probe) Validate() error {
+	var kinds []string
+	if p.HTTP.URL != "" {
+		kinds = append(kinds, "http")
+	}
+	if p.TCP != "" {
+		kinds = append(kinds, "tcp")
+	}
+	if p.SQL.Connection != "" {
+		kinds = append(kinds, "sql")
+	}
+	if len(p.Command.Args) > 0 {
+		kinds = append(kinds, "command")
+	}
+
+	if len(kinds) != 1 {
+		return fmt.Errorf("probe %v: exactly one of `http`, `tcp`, `sql` or `command` is required, got %v", p.String(), len(kinds))
+	}
+
+	for _, expr := range []string{p.HTTP.Body, p.SQL.Expect, p.Expect} {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("probe %v: invalid regular expression %q: %v", p.String(), expr, err)
+		}
+	}
+
+	if p.TCP != "" {
+		if _, _, err := net.SplitHostPort(p.TCP); err != nil {
+			return fmt.Errorf("probe %v: %v", p.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// String returns the name of the probe or a description of its target.
+func (p Probe) String() string {
+	switch {
+	case p.Name != "":
+		return p.Name
+	case p.HTTP.URL != "":
+		return "GET " + p.HTTP.URL
+	case p.TCP != "":
+		return "tcp " + p.TCP
+	case p.SQL.Connection != "":
+		return "sql " + p.SQL.Connection
+	}
+
+	return p.Command.String()
+}
+
+// Run runs the probe until an attempt succeeds or every attempt failed.
+func (p Probe) Run(ctx context.Context, executor runner.Executor) Result {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	res := Result{Name: p.String()}
+	start := time.Now()
+
+	for {
+		res.Attempts++
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		res.Err = p.attempt(attemptCtx, executor)
+		cancel()
+
+		if res.Err == nil || res.Attempts > p.Retries {
+			res.Duration = time.Since(start)
+			return res
+		}
+
+		select {
+		case <-ctx.Done():
+			res.Err = fmt.Errorf("%v (%v)", res.Err, ctx.Err())
+			res.Duration = time.Since(start)
+			return res
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (p Probe) attempt(ctx context.Context, executor runner.Executor) error {
+	switch {
+	case p.HTTP.URL != "":
+		return p.HTTP.probe(ctx)
+	case p.TCP != "":
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", p.TCP)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case p.SQL.Connection != "":
+		return p.SQL.probe(ctx, executor)
+	}
+
+	res, err := executor.Run(ctx, p.Command)
+	if err != nil {
+		return err
+	}
+
+	return expect(p.Expect, res.Stdout)
+}
+
+func (h HTTP) probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+	if h.Insecure {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	status := h.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("got status %v, expected %v", resp.Status, status)
+	}
+
+	// Only the beginning of the body is matched, health endpoints are small.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	return expect(h.Body, string(body))
+}
+
+func (s SQL) probe(ctx context.Context, executor runner.Executor) error {
+	cmd := s.Command
+	if len(cmd.Args) == 0 {
+		cmd = runner.NewCommand("psql")
+	}
+
+	query := s.Query
+	if query == "" {
+		query = "SELECT 1"
+	}
+
+	cmd.Args = append(append([]string{}, cmd.Args...), "-X", "-A", "-t", "-q", "-d", s.Connection, "-c", query)
+
+	res, err := executor.Run(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	return expect(s.Expect, strings.TrimSpace(res.Stdout))
+}
+
+// expect returns an error if the output does not match the regular expression. An empty expression matches anything.
+func expect(expr, output string) error {
+	if expr == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+
+	if !re.MatchString(output) {
+		return fmt.Errorf("output %q does not match %q", truncate(output, 200), expr)
+	}
+
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n] + "..."
+}
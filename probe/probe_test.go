@@ -0,0 +1,257 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+func TestProbeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   Probe
+		wantErr bool
+	}{
+		{"http", Probe{HTTP: HTTP{URL: "http://dw-vip/health", Body: "^OK"}}, false},
+		{"tcp", Probe{TCP: "dw-vip:5432"}, false},
+		{"sql", Probe{SQL: SQL{Connection: "host=pkm-vip", Expect: "^f$"}}, false},
+		{"command", Probe{Command: runner.NewCommand("systemctl", "is-active", "dwapp"), Expect: "active"}, false},
+		{"none", Probe{}, true},
+		{"two kinds", Probe{TCP: "dw-vip:5432", Command: runner.NewCommand("true")}, true},
+		{"tcp without port", Probe{TCP: "dw-vip"}, true},
+		{"invalid body", Probe{HTTP: HTTP{URL: "http://dw-vip", Body: "(OK"}}, true},
+		{"invalid sql expect", Probe{SQL: SQL{Connection: "host=pkm-vip", Expect: "[f"}}, true},
+		{"invalid expect", Probe{Command: runner.NewCommand("true"), Expect: "*"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.probe.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%v: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			fmt.Fprint(w, "OK")
+		case "/starting":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, "starting")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	tests := []struct {
+		name    string
+		http    HTTP
+		wantErr string
+	}{
+		{"ok", HTTP{URL: server.URL + "/health"}, ""},
+		{"body", HTTP{URL: server.URL + "/health", Body: "^OK$"}, ""},
+		{"body mismatch", HTTP{URL: server.URL + "/health", Body: "^healthy$"}, `output "OK" does not match "^healthy$"`},
+		{"status mismatch", HTTP{URL: server.URL + "/starting"}, "got status 503 Service Unavailable, expected 200"},
+		{"expected status", HTTP{URL: server.URL + "/starting", Status: http.StatusServiceUnavailable, Body: "starting"}, ""},
+		{"not found", HTTP{URL: server.URL + "/missing"}, "got status 404 Not Found, expected 200"},
+		{"insecure", HTTP{URL: tlsServer.URL + "/health", Insecure: true}, ""},
+		{"untrusted certificate", HTTP{URL: tlsServer.URL + "/health"}, "certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Probe{HTTP: tt.http}.Run(context.Background(), &runner.Fake{})
+			checkResult(t, res, tt.wantErr)
+		})
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// A port nothing listens on, the listener is closed right away.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	res := Probe{TCP: l.Addr().String()}.Run(context.Background(), &runner.Fake{})
+	checkResult(t, res, "")
+
+	res = Probe{TCP: closedAddr}.Run(context.Background(), &runner.Fake{})
+	checkResult(t, res, "connection refused")
+}
+
+func TestProbeSQL(t *testing.T) {
+	const cmd = "sudo -u postgres psql -X -A -t -q -d host=pkm-vip -c SELECT pg_is_in_recovery()"
+
+	tests := []struct {
+		name    string
+		result  runner.Result
+		wantErr string
+	}{
+		{"primary", runner.Result{Stdout: "f\n"}, ""},
+		{"standby", runner.Result{Stdout: "t\n"}, `output "t" does not match "^f$"`},
+		{"psql failure", runner.Result{Stderr: "connection refused", ExitCode: 2}, "exit code 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &runner.Fake{Results: map[string]runner.Result{cmd: tt.result}}
+			p := Probe{SQL: SQL{
+				Connection: "host=pkm-vip",
+				Query:      "SELECT pg_is_in_recovery()",
+				Expect:     "^f$",
+				Command:    runner.NewCommand("sudo", "-u", "postgres", "psql"),
+			}}
+
+			checkResult(t, p.Run(context.Background(), fake), tt.wantErr)
+		})
+	}
+
+	// The default query is `SELECT 1`.
+	fake := &runner.Fake{Results: map[string]runner.Result{"psql -X -A -t -q -d host=pkm-vip -c SELECT 1": {Stdout: "1\n"}}}
+	checkResult(t, Probe{SQL: SQL{Connection: "host=pkm-vip"}}.Run(context.Background(), fake), "")
+}
+
+func TestProbeCommand(t *testing.T) {
+	fake := &runner.Fake{Results: map[string]runner.Result{
+		"systemctl is-active dwapp": {Stdout: "active\n"},
+		"systemctl is-active dwdb":  {Stdout: "inactive\n", ExitCode: 3},
+	}}
+
+	tests := []struct {
+		name    string
+		probe   Probe
+		wantErr string
+	}{
+		{"active", Probe{Command: runner.NewCommand("systemctl", "is-active", "dwapp"), Expect: "^active"}, ""},
+		{"output mismatch", Probe{Command: runner.NewCommand("systemctl", "is-active", "dwapp"), Expect: "^inactive"}, `does not match "^inactive"`},
+		{"exit code", Probe{Command: runner.NewCommand("systemctl", "is-active", "dwdb")}, "exit code 3"},
+		{"not found", Probe{Command: runner.NewCommand("dwctl", "status")}, "exit code 127"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResult(t, tt.probe.Run(context.Background(), fake), tt.wantErr)
+		})
+	}
+}
+
+func TestProbeRetries(t *testing.T) {
+	tests := []struct {
+		retries      int
+		wantAttempts int
+		wantErr      string
+	}{
+		{0, 1, "not ready"},
+		{1, 2, "not ready"},
+		{2, 3, ""},
+		{5, 3, ""},
+	}
+
+	for _, tt := range tests {
+		attempts := 0
+		fake := &runner.Fake{Handler: func(ctx context.Context, cmd runner.Command) (runner.Result, error) {
+			attempts++
+			if attempts < 3 {
+				res := runner.Result{Command: cmd, ExitCode: 1}
+				return res, &runner.Error{Result: res, Err: errors.New("not ready")}
+			}
+			return runner.Result{Command: cmd}, nil
+		}}
+
+		p := Probe{Command: runner.NewCommand("dwctl", "status"), Retries: tt.retries, Interval: time.Millisecond}
+		res := p.Run(context.Background(), fake)
+		if res.Attempts != tt.wantAttempts {
+			t.Errorf("Run() with %v retries made %v attempt(s), want %v", tt.retries, res.Attempts, tt.wantAttempts)
+		}
+		checkResult(t, res, tt.wantErr)
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := Probe{HTTP: HTTP{URL: server.URL}, Timeout: 50 * time.Millisecond, Retries: 1, Interval: time.Millisecond}
+	res := p.Run(context.Background(), &runner.Fake{})
+
+	checkResult(t, res, "context deadline exceeded")
+	if res.Attempts != 2 {
+		t.Errorf("Run() made %v attempt(s), want 2", res.Attempts)
+	}
+	if res.Duration >= 5*time.Second {
+		t.Errorf("Run() took %v, the attempts should time out after 50ms", res.Duration)
+	}
+}
+
+func TestProbeCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fake := &runner.Fake{Handler: func(_ context.Context, cmd runner.Command) (runner.Result, error) {
+		cancel()
+		res := runner.Result{Command: cmd, ExitCode: 1}
+		return res, &runner.Error{Result: res, Err: errors.New("not ready")}
+	}}
+
+	// The probe gives up while waiting for the next attempt.
+	p := Probe{Command: runner.NewCommand("dwctl", "status"), Retries: 5, Interval: time.Hour}
+	res := p.Run(ctx, fake)
+
+	checkResult(t, res, "context canceled")
+	if res.Attempts != 1 {
+		t.Errorf("Run() made %v attempt(s), want 1", res.Attempts)
+	}
+}
+
+// checkResult fails the test if the result is not successful when wantErr is empty, or if its error does
+// not contain wantErr otherwise.
+func checkResult(t *testing.T, res Result, wantErr string) {
+	t.Helper()
+
+	switch {
+	case wantErr == "" && res.Err != nil:
+		t.Errorf("Run() = %v, want success", res)
+	case wantErr != "" && res.Err == nil:
+		t.Errorf("Run() = %v, want an error containing %q", res, wantErr)
+	case wantErr != "" && !strings.Contains(res.Err.Error(), wantErr):
+		t.Errorf("Run() = %v, want an error containing %q", res, wantErr)
+	}
+}
@@ -0,0 +1,65 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+// Result is the outcome of a probe.
+type Result struct {
+	Name     string
+	Attempts int
+	Duration time.Duration
+	// Err is the error of the last attempt, nil if the probe succeeded.
+	Err error
+}
+
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("[FAILED] %v after %v attempt(s) in %v: %v", r.Name, r.Attempts, r.Duration.Round(time.Millisecond), r.Err)
+	}
+
+	return fmt.Sprintf("[OK] %v after %v attempt(s) in %v", r.Name, r.Attempts, r.Duration.Round(time.Millisecond))
+}
+
+// Results are the outcomes of the probes of a cluster.
+type Results []Result
+
+// RunAll runs the probes one after the other and returns their results.
+func RunAll(ctx context.Context, executor runner.Executor, probes []Probe) Results {
+	var results Results
+	for _, p := range probes {
+		results = append(results, p.Run(ctx, executor))
+	}
+
+	return results
+}
+
+// Err returns an error wrapping `ErrProbeFailed` listing the failed probes or nil if every probe succeeded.
+func (rs Results) Err() error {
+	var failed []string
+	for _, r := range rs {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", r.Name, r.Err))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrProbeFailed, strings.Join(failed, "; "))
+}
+
+func (rs Results) String() string {
+	var str string
+	for _, r := range rs {
+		str += r.String() + "\n"
+	}
+
+	return str
+}
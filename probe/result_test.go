@@ -0,0 +1,67 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KalebHawkins/gofailover/runner"
+)
+
+func TestRunAll(t *testing.T) {
+	fake := &runner.Fake{Results: map[string]runner.Result{
+		"systemctl is-active dwapp": {Stdout: "active\n"},
+	}}
+
+	probes := []Probe{
+		{Name: "dwapp", Command: runner.NewCommand("systemctl", "is-active", "dwapp")},
+		{Command: runner.NewCommand("systemctl", "is-active", "dwdb")},
+	}
+
+	results := RunAll(context.Background(), fake, probes)
+	if len(results) != 2 {
+		t.Fatalf("RunAll() returned %v result(s), want 2", len(results))
+	}
+	if results[0].Name != "dwapp" || results[0].Err != nil {
+		t.Errorf("RunAll()[0] = %v, want dwapp to succeed", results[0])
+	}
+	if results[1].Name != "systemctl is-active dwdb" || results[1].Err == nil {
+		t.Errorf("RunAll()[1] = %v, want systemctl is-active dwdb to fail", results[1])
+	}
+
+	err := results.Err()
+	if !errors.Is(err, ErrProbeFailed) {
+		t.Errorf("errors.Is(%v, ErrProbeFailed) = false, want true", err)
+	}
+	if err != nil && (strings.Contains(err.Error(), "dwapp:") || !strings.Contains(err.Error(), "systemctl is-active dwdb:")) {
+		t.Errorf("Results.Err() = %v, want only the failed probe listed", err)
+	}
+
+	if err := results[:1].Err(); err != nil {
+		t.Errorf("Results.Err() = %v, want nil", err)
+	}
+}
+
+func TestResultString(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{Result{Name: "web ui", Attempts: 1, Duration: 12300 * time.Microsecond}, "[OK] web ui after 1 attempt(s) in 12ms"},
+		{Result{Name: "tcp dw-vip:5432", Attempts: 3, Duration: 10 * time.Second, Err: errors.New("connection refused")},
+			"[FAILED] tcp dw-vip:5432 after 3 attempt(s) in 10s: connection refused"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.result.String(); got != tt.want {
+			t.Errorf("Result.String() = %q, want %q", got, tt.want)
+		}
+	}
+
+	results := Results{tests[0].result, tests[1].result}
+	if got, want := results.String(), tests[0].want+"\n"+tests[1].want+"\n"; got != want {
+		t.Errorf("Results.String() = %q, want %q", got, want)
+	}
+}
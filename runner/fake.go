@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Fake is an `Executor` that does not run anything. It returns canned results for commands and
+// records every command it is asked to run. It is used to exercise the failover flow without pacemaker.
+//
+// The `Dialog` of an interactive command is run against the canned standard output, e.g. a recorded
+// transcript of the command. What the dialog writes is recorded as the `Stdin` of the command in `Calls`.
+type Fake struct {
+	// Results maps a command line (see `Command.String`) to its result. A non-zero exit code is
+	// returned as an `*Error`.
+	Results map[string]Result
+	// Handler is called for commands that are not in `Results`. Commands without a result or
+	// handler fail as if they were not found.
+	Handler func(ctx context.Context, cmd Command) (Result, error)
+
+	mu    sync.Mutex
+	calls []Command
+}
+
+// Fake.Run() records the command and returns its canned result. An error is returned without a result
+// if the context is already done.
+func (f *Fake) Run(ctx context.Context, cmd Command) (Result, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, cmd)
+	call := len(f.calls) - 1
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		res := Result{Command: cmd, ExitCode: -1}
+		return res, &Error{Result: res, Err: err}
+	}
+
+	if res, ok := f.Results[cmd.String()]; ok {
+		res.Command = cmd
+		if cmd.Dialog != nil {
+			var stdin bytes.Buffer
+			err := cmd.Dialog(strings.NewReader(res.Stdout), &stdin)
+
+			f.mu.Lock()
+			f.calls[call].Stdin = stdin.String()
+			f.mu.Unlock()
+
+			if err != nil {
+				res.ExitCode = -1
+				return res, &Error{Result: res, Err: err}
+			}
+		}
+		if res.ExitCode != 0 {
+			return res, &Error{Result: res, Err: fmt.Errorf("exit status %v", res.ExitCode)}
+		}
+		return res, nil
+	}
+
+	if f.Handler != nil {
+		return f.Handler(ctx, cmd)
+	}
+
+	res := Result{Command: cmd, ExitCode: 127}
+	return res, &Error{Result: res, Err: errors.New("executable file not found")}
+}
+
+// Fake.Calls() returns the commands run so far.
+func (f *Fake) Calls() []Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Command(nil), f.calls...)
+}
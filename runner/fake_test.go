@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestFakeDialog(t *testing.T) {
+	fake := &Fake{Results: map[string]Result{
+		"pg-rex_switchover": {Stdout: "Continue? (y/n) \nSwitchover completed.\n"},
+	}}
+
+	cmd := NewCommand("pg-rex_switchover")
+	cmd.Dialog = func(stdout io.Reader, stdin io.Writer) error {
+		if _, err := readUntil(stdout, "(y/n) "); err != nil {
+			return err
+		}
+		_, err := io.WriteString(stdin, "y\n")
+		return err
+	}
+
+	if _, err := fake.Run(context.Background(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || calls[0].Stdin != "y\n" {
+		t.Errorf("Calls() = %+v, want the answer recorded as the standard input", calls)
+	}
+
+	// The transcript ends before the prompt.
+	fake.Results["pg-rex_switchover"] = Result{Stdout: "Starting...\n"}
+	_, err := fake.Run(context.Background(), cmd)
+	if !errors.Is(err, ErrCommandFailed) || !errors.Is(err, io.EOF) {
+		t.Errorf("Run() error = %v, want the error of the dialog", err)
+	}
+}
@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// OS executes commands on the local system.
+type OS struct {
+	// Output receives the output of commands with `Stream` set. Nothing is streamed if it is nil.
+	Output io.Writer
+}
+
+// OS.Run() executes the command and waits for it to finish. The command is killed if the context is done first.
+func (o *OS) Run(ctx context.Context, cmd Command) (Result, error) {
+	res := Result{Command: cmd, ExitCode: -1}
+
+	if len(cmd.Args) == 0 {
+		return res, &Error{Result: res, Err: errors.New("no command given")}
+	}
+
+	// The command is also killed when its dialog fails.
+	cmdCtx, kill := context.WithCancel(ctx)
+	defer kill()
+
+	osCmd := exec.CommandContext(cmdCtx, cmd.Args[0], cmd.Args[1:]...)
+
+	var stdout, stderr bytes.Buffer
+	osCmd.Stdout = &stdout
+	osCmd.Stderr = &stderr
+	if cmd.Stream && o.Output != nil {
+		osCmd.Stdout = io.MultiWriter(&stdout, o.Output)
+		osCmd.Stderr = io.MultiWriter(&stderr, o.Output)
+	}
+
+	var dialog *dialogPipe
+	if cmd.Dialog != nil {
+		var err error
+		if dialog, err = newDialogPipe(osCmd); err != nil {
+			return res, &Error{Result: res, Err: err}
+		}
+	} else if cmd.RepeatStdin && cmd.Stdin != "" {
+		osCmd.Stdin = &repeatReader{s: cmd.Stdin}
+	} else if cmd.Stdin != "" {
+		osCmd.Stdin = strings.NewReader(cmd.Stdin)
+	}
+
+	start := time.Now()
+	err := osCmd.Start()
+	if err == nil {
+		if dialog != nil {
+			dialog.start(cmd.Dialog, kill)
+		}
+		err = osCmd.Wait()
+	}
+	if dialog != nil {
+		// The dialog's error explains why the command was killed.
+		if dialogErr := dialog.wait(); dialogErr != nil {
+			err = dialogErr
+		}
+	}
+	res.Duration = time.Since(start)
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+
+	if osCmd.ProcessState != nil {
+		res.ExitCode = osCmd.ProcessState.ExitCode()
+	}
+
+	// Report why the command was killed rather than just `signal: killed`.
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return res, &Error{Result: res, Err: err}
+	}
+
+	return res, nil
+}
+
+// dialogPipe connects a `Dialog` to the output and input of a command.
+type dialogPipe struct {
+	out   *io.PipeReader
+	outW  *io.PipeWriter
+	in    io.WriteCloser
+	done  chan error
+	began bool
+}
+
+func newDialogPipe(osCmd *exec.Cmd) (*dialogPipe, error) {
+	in, err := osCmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &dialogPipe{in: in, done: make(chan error, 1)}
+	d.out, d.outW = io.Pipe()
+	osCmd.Stdout = io.MultiWriter(osCmd.Stdout, d.outW)
+
+	return d, nil
+}
+
+// start runs the dialog until it returns, killing the command if it fails.
+func (d *dialogPipe) start(dialog Dialog, kill func()) {
+	d.began = true
+	go func() {
+		err := dialog(d.out, d.in)
+		d.in.Close()
+		if err != nil {
+			kill()
+		}
+		// Keep reading so the command is not blocked writing its output.
+		io.Copy(io.Discard, d.out)
+		d.done <- err
+	}()
+}
+
+// wait closes the output of the command and returns the error of the dialog.
+func (d *dialogPipe) wait() error {
+	d.outW.Close()
+	if !d.began {
+		return nil
+	}
+
+	return <-d.done
+}
+
+// repeatReader returns the same string over and over again. It is used in place of `yes` for
+// commands that ask for confirmation.
+type repeatReader struct {
+	s   string
+	off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.s[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.s)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a buffer the output and the error output of a command can be streamed to concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRepeatReader(t *testing.T) {
+	r := &repeatReader{s: "y\n"}
+
+	for _, size := range []int{1, 2, 3, 7} {
+		p := make([]byte, size)
+		n, err := r.Read(p)
+		if n != size || err != nil {
+			t.Fatalf("Read() = %v, %v, want %v, nil", n, err, size)
+		}
+	}
+
+	// 13 bytes were read so far, the next read starts in the middle of "y\n".
+	p := make([]byte, 5)
+	r.Read(p)
+	if got, want := string(p), "\ny\ny\n"; got != want {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+}
+
+func TestOSRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        Command
+		wantStdout string
+		wantStderr string
+		wantExit   int
+		wantErr    bool
+	}{
+		{"stdout", NewCommand("echo", "hello"), "hello\n", "", 0, false},
+		{"stderr and exit code", NewCommand("sh", "-c", "echo oops >&2; exit 3"), "", "oops\n", 3, true},
+		{"stdin", Command{Args: []string{"cat"}, Stdin: "y\n"}, "y\n", "", 0, false},
+		{"repeated stdin", Command{Args: []string{"head", "-n", "3"}, Stdin: "y\n", RepeatStdin: true}, "y\ny\ny\n", "", 0, false},
+		{"not found", NewCommand("gofailover-does-not-exist"), "", "", -1, true},
+		{"empty", Command{}, "", "", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := (&OS{}).Run(context.Background(), tt.cmd)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCommandFailed) {
+				t.Errorf("errors.Is(%v, ErrCommandFailed) = false, want true", err)
+			}
+			if res.Stdout != tt.wantStdout || res.Stderr != tt.wantStderr || res.ExitCode != tt.wantExit {
+				t.Errorf("Run() = stdout %q, stderr %q, exit code %v, want %q, %q, %v",
+					res.Stdout, res.Stderr, res.ExitCode, tt.wantStdout, tt.wantStderr, tt.wantExit)
+			}
+		})
+	}
+}
+
+func TestOSRunStream(t *testing.T) {
+	var out syncBuffer
+	o := &OS{Output: &out}
+
+	if _, err := o.Run(context.Background(), NewCommand("echo", "quiet")); err != nil {
+		t.Fatal(err)
+	}
+	res, err := o.Run(context.Background(), Command{Args: []string{"sh", "-c", "echo out; echo err >&2"}, Stream: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Stdout != "out\n" || res.Stderr != "err\n" {
+		t.Errorf("Run() = stdout %q, stderr %q, want %q, %q", res.Stdout, res.Stderr, "out\n", "err\n")
+	}
+	// Only the streamed command is written to the output.
+	if got := out.String(); strings.Contains(got, "quiet") || !strings.Contains(got, "out\n") || !strings.Contains(got, "err\n") {
+		t.Errorf("Run() streamed %q, want the output of the streamed command only", got)
+	}
+}
+
+// readUntil reads from r until the output ends with the suffix.
+func readUntil(r io.Reader, suffix string) (string, error) {
+	var out []byte
+	buf := make([]byte, 1)
+	for !strings.HasSuffix(string(out), suffix) {
+		if _, err := r.Read(buf); err != nil {
+			return string(out), err
+		}
+		out = append(out, buf[0])
+	}
+	return string(out), nil
+}
+
+func TestOSRunDialog(t *testing.T) {
+	errPrompt := errors.New("unexpected prompt")
+
+	tests := []struct {
+		name       string
+		script     string
+		dialog     Dialog
+		wantStdout string
+		wantErr    error
+	}{
+		{
+			name:   "answers the prompt",
+			script: `printf "Continue? (y/n) "; read answer; echo "got $answer"`,
+			dialog: func(stdout io.Reader, stdin io.Writer) error {
+				if _, err := readUntil(stdout, "(y/n) "); err != nil {
+					return err
+				}
+				_, err := io.WriteString(stdin, "y\n")
+				return err
+			},
+			wantStdout: "Continue? (y/n) got y\n",
+		},
+		{
+			name:   "failing dialog kills the command",
+			script: `echo "Really? (yes/no)"; exec sleep 30`,
+			dialog: func(stdout io.Reader, stdin io.Writer) error {
+				readUntil(stdout, "\n")
+				return errPrompt
+			},
+			wantStdout: "Really? (yes/no)\n",
+			wantErr:    errPrompt,
+		},
+		{
+			name:   "output after the dialog returns",
+			script: `for i in $(seq 1 20000); do echo "line $i"; done; echo done`,
+			dialog: func(stdout io.Reader, stdin io.Writer) error {
+				return nil
+			},
+		},
+		{
+			name:   "stdin is closed when the dialog returns",
+			script: `cat; echo eof`,
+			dialog: func(stdout io.Reader, stdin io.Writer) error {
+				_, err := io.WriteString(stdin, "bye\n")
+				return err
+			},
+			wantStdout: "bye\neof\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Command{Args: []string{"sh", "-c", tt.script}, Dialog: tt.dialog}
+
+			start := time.Now()
+			res, err := (&OS{}).Run(context.Background(), cmd)
+			if time.Since(start) > 10*time.Second {
+				t.Errorf("Run() took %v, the command was not killed", time.Since(start))
+			}
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Run() error = %v, want nil", err)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantStdout != "" && res.Stdout != tt.wantStdout {
+				t.Errorf("Run() stdout = %q, want %q", res.Stdout, tt.wantStdout)
+			}
+			if tt.wantStdout == "" && !strings.HasSuffix(res.Stdout, "line 20000\ndone\n") {
+				t.Errorf("Run() stdout ends with %q, want the whole output", res.Stdout[len(res.Stdout)-20:])
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package runner runs external commands such as `crm_mon`, `pcs` and `pg-rex_switchover`.
+// Commands are executed as argv slices without a shell and their output, exit code and duration
+// are returned as a structured `Result`. The `Fake` executor allows the failover flow to be
+// exercised without pacemaker installed.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Command is an external command executed by an `Executor`.
+type Command struct {
+	// Args contains the program and its arguments, e.g. `[]string{"pcs", "resource", "move", "dwgrp"}`.
+	Args []string `mapstructure:"args"`
+	// Stdin is written to the standard input of the command.
+	Stdin string `mapstructure:"stdin"`
+	// RepeatStdin writes `Stdin` to the command over and over again, like piping `yes` into it.
+	RepeatStdin bool `mapstructure:"repeatStdin"`
+	// Stream copies the output of the command to the executor's output while it runs.
+	Stream bool `mapstructure:"stream"`
+	// Dialog drives an interactive command. It replaces `Stdin` and cannot be set in the configuration file.
+	Dialog Dialog `mapstructure:"-"`
+}
+
+// Dialog reads the standard output of an interactive command as it is written and answers its prompts
+// by writing to its standard input. The standard input is closed when the dialog returns. If an error
+// is returned the command is killed and the error is returned by the executor.
+type Dialog func(stdout io.Reader, stdin io.Writer) error
+
+// NewCommand returns a command running the program with the arguments.
+func NewCommand(args ...string) Command {
+	return Command{Args: args}
+}
+
+// String returns the command line of the command.
+func (c Command) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Result contains the outcome of a command.
+type Result struct {
+	Command  Command
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+}
+
+// Executor runs commands. An error is returned if the command could not be started or it exited with
+// a non-zero exit code, in which case the error is an `*Error` carrying the result. The command is killed
+// if the context is done before it finishes.
+type Executor interface {
+	Run(ctx context.Context, cmd Command) (Result, error)
+}
+
+// ErrCommandFailed is matched by every `*Error` with `errors.Is`.
+var ErrCommandFailed = errors.New("command failed")
+
+// Error is returned when a command fails. It contains the result of the command, including the
+// standard error output and the exit code.
+type Error struct {
+	Result Result
+	Err    error
+}
+
+func (e *Error) Error() string {
+	str := fmt.Sprintf("command `%v` failed with exit code %v after %v: %v", e.Result.Command, e.Result.ExitCode, e.Result.Duration.Round(time.Millisecond), e.Err)
+
+	if stderr := strings.TrimSpace(e.Result.Stderr); stderr != "" {
+		str += "\n\nStandard Error:\n" + stderr
+	}
+
+	return str
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is `ErrCommandFailed`.
+func (e *Error) Is(target error) bool {
+	return target == ErrCommandFailed
+}
+
+// ParseCommand splits a command line into a `Command`. Arguments are separated by whitespace and
+// can be quoted with single or double quotes. No other shell features, like pipes or variables, are supported.
+func ParseCommand(line string) (Command, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return Command{}, fmt.Errorf("unterminated quote in command `%v`", line)
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	if len(args) == 0 {
+		return Command{}, fmt.Errorf("empty command")
+	}
+
+	return Command{Args: args}, nil
+}
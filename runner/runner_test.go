@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"pcs resource move dwgrp", []string{"pcs", "resource", "move", "dwgrp"}, false},
+		{"  pcs\tresource   cleanup \n", []string{"pcs", "resource", "cleanup"}, false},
+		{`psql -c "SELECT 1"`, []string{"psql", "-c", "SELECT 1"}, false},
+		{`psql -c 'SELECT "x"'`, []string{"psql", "-c", `SELECT "x"`}, false},
+		{`echo "it's"`, []string{"echo", "it's"}, false},
+		{`echo "" ''`, []string{"echo", "", ""}, false},
+		{`--name="dw app"`, []string{"--name=dw app"}, false},
+		{`"a"'b'c`, []string{"abc"}, false},
+		// Backslashes are not escapes, they are kept as is.
+		{`echo a\ b`, []string{"echo", `a\`, "b"}, false},
+		{`echo "a\"b"`, nil, true},
+		{`echo "unterminated`, nil, true},
+		{`echo 'unterminated`, nil, true},
+		{"", nil, true},
+		{" \t\n", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCommand(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCommand(%q) error = %v, want error %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got.Args, tt.want) {
+			t.Errorf("ParseCommand(%q) = %q, want %q", tt.line, got.Args, tt.want)
+		}
+	}
+}
+
+func TestCommandString(t *testing.T) {
+	cmd := NewCommand("pcs", "resource", "move", "dwgrp")
+	if got, want := cmd.String(), "pcs resource move dwgrp"; got != want {
+		t.Errorf("Command.String() = %q, want %q", got, want)
+	}
+}
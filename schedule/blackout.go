@@ -0,0 +1,284 @@
+package schedule
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Modes of the blackouts.
+const (
+	// ModeSkip skips an action falling in a blackout.
+	ModeSkip = "skip"
+	// ModeShift moves an action falling in a blackout to the next allowed date.
+	ModeShift = "shift"
+)
+
+// DefaultMaxShift is the number of days an action is shifted at most when `Blackouts.MaxShift` is not set.
+const DefaultMaxShift = 14
+
+// Blackouts are the dates and times no action may be performed on. An action of a rule falling in a blackout
+// is skipped or, with the `shift` mode, moved to the next date that is not blacked out. Shifting needs the
+// run to be scheduled every day since the action is performed by the run of the next allowed date.
+//
+// Example config:
+//
+//	blackouts:
+//	  mode: shift
+//	  dates:
+//	    - name: year-end freeze
+//	      from: 12-15
+//	      to: 01-05
+//	    - name: datacenter move
+//	      from: 2026-11-20
+//	      to: 2026-11-22
+//	    - name: month-end close
+//	      rrule: "FREQ=MONTHLY;BYMONTHDAY=-2,-1"
+//	  ics:
+//	    - /appl/failover/holidays.ics
+//	  windows:
+//	    - weekdays: [Saturday, Sunday]
+//	      from: "02:00"
+//	      to: "06:00"
+type Blackouts struct {
+	// Mode is `skip`, the default, or `shift`.
+	Mode string `mapstructure:"mode"`
+	// MaxShift is the number of days an action is shifted at most, `DefaultMaxShift` by default.
+	MaxShift int        `mapstructure:"maxShift"`
+	Dates    []Blackout `mapstructure:"dates"`
+	// ICS are iCalendar files whose events are blackouts, e.g. a calendar of public holidays.
+	ICS []string `mapstructure:"ics"`
+	// Windows are the times of day actions are allowed in. Any time is allowed if empty. A date on whose
+	// weekday no window is open is blacked out.
+	Windows []Window `mapstructure:"windows"`
+}
+
+// Blackout is a range of dates, from `From` to `To` included, or the dates of a recurrence rule.
+// Dates are written `2006-01-02`, or `01-02` for a range recurring every year.
+type Blackout struct {
+	Name  string `mapstructure:"name"`
+	From  string `mapstructure:"from"`
+	To    string `mapstructure:"to"`
+	RRule string `mapstructure:"rrule"`
+}
+
+// Window is a time of day, from `From` included to `To` excluded, written `15:04`. `To` may be `24:00`.
+// The window is open every day unless `Weekdays` is set.
+type Window struct {
+	Weekdays []string `mapstructure:"weekdays"`
+	From     string   `mapstructure:"from"`
+	To       string   `mapstructure:"to"`
+}
+
+// period is a compiled blackout.
+type period struct {
+	name string
+	// from and to are the first and last dates of the period. Recurring periods are in year 0.
+	from, to time.Time
+	yearly   bool
+	// rrule starts a period of `days` days on each of its occurrences, except on the dates of `except`.
+	rrule  *RRule
+	days   int
+	except []time.Time
+}
+
+// window is a compiled window, in minutes since midnight.
+type window struct {
+	weekdays map[time.Weekday]bool
+	from, to int
+}
+
+// compile validates the blackouts and reads their iCalendar files.
+func (b Blackouts) compile() ([]period, []window, error) {
+	switch b.Mode {
+	case "", ModeSkip, ModeShift:
+	default:
+		return nil, nil, fmt.Errorf("unknown mode %q, expected %v or %v", b.Mode, ModeSkip, ModeShift)
+	}
+
+	if b.MaxShift < 0 {
+		return nil, nil, fmt.Errorf("maxShift must not be negative")
+	}
+
+	var periods []period
+	for i, bo := range b.Dates {
+		p, err := bo.compile()
+		if err != nil {
+			return nil, nil, fmt.Errorf("blackout %v: %v", i+1, err)
+		}
+		periods = append(periods, p)
+	}
+
+	for _, path := range b.ICS {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		events, err := parseICS(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", path, err)
+		}
+		periods = append(periods, events...)
+	}
+
+	var windows []window
+	for i, w := range b.Windows {
+		cw, err := w.compile()
+		if err != nil {
+			return nil, nil, fmt.Errorf("window %v: %v", i+1, err)
+		}
+		windows = append(windows, cw)
+	}
+
+	return periods, windows, nil
+}
+
+func (b Blackout) compile() (period, error) {
+	p := period{name: b.Name}
+
+	if b.RRule != "" {
+		if b.From != "" || b.To != "" {
+			return p, fmt.Errorf("`rrule` cannot be combined with `from` and `to`")
+		}
+
+		rr, err := ParseRRule(b.RRule)
+		if err != nil {
+			return p, err
+		}
+		p.rrule, p.days = &rr, 1
+
+		if p.name == "" {
+			p.name = "rrule " + b.RRule
+		}
+		return p, nil
+	}
+
+	if b.From == "" {
+		return p, fmt.Errorf("`from` or `rrule` is required")
+	}
+
+	to := b.To
+	if to == "" {
+		to = b.From
+	}
+
+	var err error
+	if p.from, err = time.Parse("2006-01-02", b.From); err == nil {
+		if p.to, err = time.Parse("2006-01-02", to); err != nil {
+			return p, fmt.Errorf("invalid date %q, expected the format of `from`: %v", to, err)
+		}
+		if p.to.Before(p.from) {
+			return p, fmt.Errorf("%v is before %v", to, b.From)
+		}
+	} else {
+		p.yearly = true
+		if p.from, err = time.Parse("01-02", b.From); err != nil {
+			return p, fmt.Errorf("invalid date %q, expected 2006-01-02 or 01-02", b.From)
+		}
+		if p.to, err = time.Parse("01-02", to); err != nil {
+			return p, fmt.Errorf("invalid date %q, expected the format of `from`", to)
+		}
+	}
+
+	if p.name == "" {
+		p.name = "blackout"
+	}
+
+	return p, nil
+}
+
+// contains returns true if the civil date is in the period.
+func (p period) contains(day time.Time) bool {
+	switch {
+	case p.rrule != nil:
+		for i := 0; i < p.days; i++ {
+			if start := day.AddDate(0, 0, -i); p.rrule.Matches(start) && !p.excepted(start) {
+				return true
+			}
+		}
+		return false
+	case p.yearly:
+		md := time.Date(0, day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+		if p.to.Before(p.from) {
+			// The period spans the new year, e.g. from 12-15 to 01-05.
+			return !md.Before(p.from) || !md.After(p.to)
+		}
+		return !md.Before(p.from) && !md.After(p.to)
+	}
+
+	return !day.Before(p.from) && !day.After(p.to)
+}
+
+// excepted returns true if the occurrence starting on the civil date is excluded.
+func (p period) excepted(start time.Time) bool {
+	for _, d := range p.except {
+		if d.Equal(start) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p period) String() string {
+	switch {
+	case p.rrule != nil:
+		return p.name
+	case p.yearly:
+		return fmt.Sprintf("%v (%v to %v, every year)", p.name, p.from.Format("Jan 2"), p.to.Format("Jan 2"))
+	case p.from.Equal(p.to):
+		return fmt.Sprintf("%v (%v)", p.name, p.from.Format("2006-01-02"))
+	}
+
+	return fmt.Sprintf("%v (%v to %v)", p.name, p.from.Format("2006-01-02"), p.to.Format("2006-01-02"))
+}
+
+func (w Window) compile() (window, error) {
+	cw := window{}
+
+	var err error
+	if cw.from, err = parseClock(w.From); err != nil {
+		return cw, err
+	}
+	if cw.to, err = parseClock(w.To); err != nil {
+		return cw, err
+	}
+	if cw.from >= cw.to {
+		return cw, fmt.Errorf("`from` %v must be before `to` %v", w.From, w.To)
+	}
+
+	if len(w.Weekdays) > 0 {
+		cw.weekdays = make(map[time.Weekday]bool)
+		for _, name := range w.Weekdays {
+			d, err := ParseWeekday(name)
+			if err != nil {
+				return cw, err
+			}
+			cw.weekdays[d] = true
+		}
+	}
+
+	return cw, nil
+}
+
+// openOn returns true if the window is open on the weekday.
+func (w window) openOn(d time.Weekday) bool {
+	return w.weekdays == nil || w.weekdays[d]
+}
+
+// parseClock parses a time of day written `15:04` into minutes since midnight. `24:00` is the end of the day.
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected 15:04", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,287 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decision is the outcome of the schedule for a date.
+type Decision struct {
+	// Date is the evaluated date.
+	Date time.Time
+	// Rule is the rule matching the date, or the rule whose action was shifted to the date. It is only
+	// meaningful if `Action` is set.
+	Rule   Rule
+	Action Action
+	// Blackout is the reason the action of the rule was blacked out, e.g. the name and dates of the blackout.
+	// The action must not be performed.
+	Blackout string
+	// ShiftedFrom is the date the action was originally scheduled on, if it was shifted to `Date`.
+	ShiftedFrom time.Time
+	// ShiftedTo is the date a blacked out action is shifted to, if any.
+	ShiftedTo time.Time
+}
+
+// Perform returns true if the action of the decision has to be performed.
+func (d Decision) Perform() bool {
+	return d.Action != "" && d.Blackout == ""
+}
+
+// String describes the decision, e.g. "failover-to-secondary (1st Sunday of the month)".
+func (d Decision) String() string {
+	switch {
+	case d.Action == "":
+		return "no rule matches"
+	case d.Blackout != "" && !d.ShiftedTo.IsZero():
+		return fmt.Sprintf("%v (%v) blacked out: %v, shifted to %v", d.Action, d.Rule, d.Blackout, d.ShiftedTo.Format("2006-01-02"))
+	case d.Blackout != "":
+		return fmt.Sprintf("%v (%v) skipped, blacked out: %v", d.Action, d.Rule, d.Blackout)
+	case !d.ShiftedFrom.IsZero():
+		return fmt.Sprintf("%v (%v) shifted from %v", d.Action, d.Rule, d.ShiftedFrom.Format("2006-01-02"))
+	}
+
+	return fmt.Sprintf("%v (%v)", d.Action, d.Rule)
+}
+
+// Calendar is a compiled schedule, see `Schedule.Compile`.
+type Calendar struct {
+	rules    []Rule
+	matchers []*matcher
+	periods  []period
+	windows  []window
+	shift    bool
+	maxShift int
+}
+
+// Compile validates the schedule and reads the iCalendar files of its blackouts.
+func (s Schedule) Compile() (*Calendar, error) {
+	c := &Calendar{rules: s.Rules, shift: s.Blackouts.Mode == ModeShift, maxShift: s.Blackouts.MaxShift}
+	if c.maxShift == 0 {
+		c.maxShift = DefaultMaxShift
+	}
+
+	for i, r := range s.Rules {
+		m, err := r.compile()
+		if err != nil {
+			return nil, fmt.Errorf("rule %v: %v", i+1, err)
+		}
+		c.matchers = append(c.matchers, m)
+	}
+
+	var err error
+	if c.periods, c.windows, err = s.Blackouts.compile(); err != nil {
+		return nil, fmt.Errorf("blackouts: %v", err)
+	}
+
+	return c, nil
+}
+
+// Evaluate returns the decision for the date and time of `t`. On top of the blackouts of the date, see
+// `EvaluateDate`, the action is blacked out if `t` is outside the allowed windows.
+func (c *Calendar) Evaluate(t time.Time) Decision {
+	d := c.EvaluateDate(t)
+	if !d.Perform() || len(c.windows) == 0 {
+		return d
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+	for _, w := range c.windows {
+		if w.openOn(t.Weekday()) && minutes >= w.from && minutes < w.to {
+			return d
+		}
+	}
+
+	d.Blackout = fmt.Sprintf("%v is outside of the allowed windows", t.Format("15:04"))
+	return d
+}
+
+// EvaluateDate returns the decision for the date of `t`, ignoring the time of day. The first rule matching the
+// date decides the action. If the date is blacked out the action is skipped, or shifted to the next date that
+// is not with the `shift` mode. A date without a matching rule gets the action of the last blacked out date
+// shifted to it, if any. A shifted action is dropped if the date it is shifted to has a matching rule.
+func (c *Calendar) EvaluateDate(t time.Time) Decision {
+	d := Decision{Date: t}
+
+	if rule, ok := c.match(t); ok {
+		d.Rule, d.Action = rule, rule.Action
+
+		if reason := c.blackout(t); reason != "" {
+			d.Blackout = reason
+			if c.shift {
+				d.ShiftedTo, d.Blackout = c.shiftTo(t, reason)
+			}
+		}
+		return d
+	}
+
+	if !c.shift || c.blackout(t) != "" {
+		return d
+	}
+
+	// The action of the most recent blacked out date whose next allowed date is today.
+	for i := 1; i <= c.maxShift; i++ {
+		from := t.AddDate(0, 0, -i)
+		if c.blackout(from) == "" {
+			// Any action before this date was shifted to this date at the latest.
+			break
+		}
+
+		if rule, ok := c.match(from); ok {
+			d.Rule, d.Action, d.ShiftedFrom = rule, rule.Action, from
+			return d
+		}
+	}
+
+	return d
+}
+
+// Between returns the decisions of the dates from `from` to `to` included that have an action, blacked out or not,
+// see `EvaluateDate`. The dates are at midnight in the location of `from`.
+func (c *Calendar) Between(from, to time.Time) []Decision {
+	var decisions []Decision
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for !day.After(to) {
+		if d := c.EvaluateDate(day); d.Action != "" {
+			decisions = append(decisions, d)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return decisions
+}
+
+// match returns the first rule matching the date of `t`.
+func (c *Calendar) match(t time.Time) (Rule, bool) {
+	for i, m := range c.matchers {
+		if m.matches(t) {
+			return c.rules[i], true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// blackout returns the reason the date of `t` is blacked out, or an empty string.
+func (c *Calendar) blackout(t time.Time) string {
+	day := civil(t)
+	for _, p := range c.periods {
+		if p.contains(day) {
+			return p.String()
+		}
+	}
+
+	if len(c.windows) == 0 {
+		return ""
+	}
+
+	for _, w := range c.windows {
+		if w.openOn(t.Weekday()) {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("no allowed window on %v", t.Weekday())
+}
+
+// shiftTo returns the date the action of the blacked out date `t` is shifted to, or the zero time along with
+// the reason if the action is dropped: when no date is allowed within `maxShift` days, or when a later rule
+// takes the place of the action.
+func (c *Calendar) shiftTo(t time.Time, reason string) (time.Time, string) {
+	next := c.nextAllowed(t)
+	if next.IsZero() {
+		return next, fmt.Sprintf("%v, no allowed date within %v days", reason, c.maxShift)
+	}
+
+	for day := t.AddDate(0, 0, 1); !day.After(next); day = day.AddDate(0, 0, 1) {
+		if _, ok := c.match(day); ok {
+			return time.Time{}, fmt.Sprintf("%v, superseded by the action of %v", reason, day.Format("2006-01-02"))
+		}
+	}
+
+	return next, reason
+}
+
+// nextAllowed returns the first date after `t` that is not blacked out, within `maxShift` days. The zero
+// time is returned if there is none.
+func (c *Calendar) nextAllowed(t time.Time) time.Time {
+	for i := 1; i <= c.maxShift; i++ {
+		next := t.AddDate(0, 0, i)
+		if c.blackout(next) == "" {
+			return next
+		}
+	}
+
+	return time.Time{}
+}
+
+// Ordinal returns the occurrence of the weekday of `t` in its month, e.g. 2 for the 2nd Sunday of the month.
+func Ordinal(t time.Time) int {
+	return (t.Day()-1)/7 + 1
+}
+
+// IsLast returns true if `t` is the last occurrence of its weekday in its month.
+func IsLast(t time.Time) bool {
+	return t.Day()+7 > DaysIn(t.Year(), t.Month())
+}
+
+// DaysIn returns the number of days in the month of the year.
+func DaysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// ParseWeekday parses the English name of a weekday, full or abbreviated to 3 letters, ignoring case.
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// ParseMonth parses the English name of a month, full or abbreviated to 3 letters, or its number.
+func ParseMonth(name string) (time.Month, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 || n > 12 {
+			return 0, fmt.Errorf("month %v is out of range", n)
+		}
+		return time.Month(n), nil
+	}
+
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if name == full || name == full[:3] {
+			return m, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown month %q", name)
+}
+
+// ordinals are the accepted spellings of the ordinals, -1 is the last occurrence.
+var ordinals = map[string]int{
+	"1st": 1, "first": 1, "1": 1,
+	"2nd": 2, "second": 2, "2": 2,
+	"3rd": 3, "third": 3, "3": 3,
+	"4th": 4, "fourth": 4, "4": 4,
+	"5th": 5, "fifth": 5, "5": 5,
+	"last": -1, "-1": -1,
+}
+
+// ParseOrdinal parses an ordinal weekday of the month: `1st` to `5th`, `first` to `fifth` or `last`.
+// The last occurrence is returned as -1.
+func ParseOrdinal(s string) (int, error) {
+	n, ok := ordinals[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown ordinal %q, expected 1st to 5th or last", s)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,152 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarEvaluate(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	cal, err := Default("").Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windowed, err := Schedule{
+		Rules: Default("").Rules,
+		Blackouts: Blackouts{Windows: []Window{
+			{Weekdays: []string{"Sunday"}, From: "02:00", To: "06:00"},
+		}},
+	}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cal      *Calendar
+		now      time.Time
+		action   Action
+		blackout string
+	}{
+		{"1st Sunday of March", cal, time.Date(2026, 3, 1, 3, 0, 0, 0, chicago), ActionFailover, ""},
+		{"2nd Sunday of March, DST starts", cal, time.Date(2026, 3, 8, 3, 30, 0, 0, chicago), ActionFailback, ""},
+		{"Monday", cal, time.Date(2026, 3, 2, 3, 0, 0, 0, chicago), "", ""},
+		// The date is the one of the time zone of the clock, not the one of UTC.
+		{"Sunday evening in Chicago", cal, time.Date(2026, 3, 2, 4, 0, 0, 0, time.UTC).In(chicago), ActionFailover, ""},
+		{"Monday morning in UTC", cal, time.Date(2026, 3, 2, 4, 0, 0, 0, time.UTC), "", ""},
+		{"1st Sunday of November, DST ends", cal, time.Date(2026, 11, 1, 1, 30, 0, 0, chicago).Add(time.Hour), ActionFailover, ""},
+		{"in the window after DST starts", windowed, time.Date(2026, 3, 8, 3, 0, 0, 0, chicago), ActionFailback, ""},
+		{"after the window after DST starts", windowed, time.Date(2026, 3, 8, 6, 0, 0, 0, chicago), ActionFailback, "06:00 is outside of the allowed windows"},
+		// 01:30 happens twice when DST ends, both are before the window.
+		{"before the window after DST ends", windowed, time.Date(2026, 11, 1, 1, 30, 0, 0, chicago).Add(time.Hour), ActionFailover, "01:30 is outside of the allowed windows"},
+	}
+
+	for _, tt := range tests {
+		d := tt.cal.Evaluate(tt.now)
+		if d.Action != tt.action || d.Blackout != tt.blackout {
+			t.Errorf("%v: Evaluate(%v) = %q blacked out %q, want %q blacked out %q", tt.name, tt.now, d.Action, d.Blackout, tt.action, tt.blackout)
+		}
+		if d.Perform() != (tt.action != "" && tt.blackout == "") {
+			t.Errorf("%v: Evaluate(%v).Perform() = %v", tt.name, tt.now, d.Perform())
+		}
+	}
+}
+
+func TestCalendarEvaluateBlackouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		blackouts Blackouts
+		date      string
+		action    Action
+		// blackout is a substring of the blackout reason, the action is not blacked out if empty.
+		blackout    string
+		shiftedTo   string
+		shiftedFrom string
+	}{
+		{
+			name:      "skipped",
+			blackouts: Blackouts{Dates: []Blackout{{Name: "freeze", From: "2026-11-01"}}},
+			date:      "2026-11-01",
+			action:    ActionFailover,
+			blackout:  "freeze (2026-11-01)",
+		},
+		{
+			name:      "yearly blackout spanning the new year",
+			blackouts: Blackouts{Dates: []Blackout{{Name: "year-end freeze", From: "12-15", To: "01-05"}}},
+			date:      "2027-01-03",
+			action:    ActionFailover,
+			blackout:  "year-end freeze",
+		},
+		{
+			name:      "shifted to the next allowed date",
+			blackouts: Blackouts{Mode: ModeShift, Dates: []Blackout{{Name: "freeze", From: "2026-11-01"}}},
+			date:      "2026-11-01",
+			action:    ActionFailover,
+			blackout:  "freeze",
+			shiftedTo: "2026-11-02",
+		},
+		{
+			name:        "shifted from the blacked out date",
+			blackouts:   Blackouts{Mode: ModeShift, Dates: []Blackout{{Name: "freeze", From: "2026-11-01"}}},
+			date:        "2026-11-02",
+			action:      ActionFailover,
+			shiftedFrom: "2026-11-01",
+		},
+		{
+			name:      "superseded by the next rule",
+			blackouts: Blackouts{Mode: ModeShift, Dates: []Blackout{{Name: "freeze", From: "2026-11-01", To: "2026-11-08"}}},
+			date:      "2026-11-01",
+			action:    ActionFailover,
+			blackout:  "superseded by the action of 2026-11-08",
+		},
+		{
+			name:      "not shifted further than maxShift",
+			blackouts: Blackouts{Mode: ModeShift, MaxShift: 3, Dates: []Blackout{{Name: "freeze", From: "2026-11-01", To: "2026-11-05"}}},
+			date:      "2026-11-01",
+			action:    ActionFailover,
+			blackout:  "no allowed date within 3 days",
+		},
+		{
+			name:      "rrule blackout",
+			blackouts: Blackouts{Dates: []Blackout{{Name: "month-end close", RRule: "FREQ=MONTHLY;BYMONTHDAY=-2,-1"}}},
+			date:      "2026-05-31",
+			action:    ActionFailback,
+			blackout:  "month-end close",
+		},
+	}
+
+	for _, tt := range tests {
+		cal, err := Schedule{Rules: Default("").Rules, Blackouts: tt.blackouts}.Compile()
+		if err != nil {
+			t.Fatalf("%v: Compile() error = %v", tt.name, err)
+		}
+
+		d := cal.EvaluateDate(date(t, tt.date))
+		if d.Action != tt.action {
+			t.Errorf("%v: EvaluateDate(%v) action = %q, want %q", tt.name, tt.date, d.Action, tt.action)
+		}
+		if tt.blackout == "" && d.Blackout != "" || !strings.Contains(d.Blackout, tt.blackout) {
+			t.Errorf("%v: EvaluateDate(%v) blacked out %q, want %q", tt.name, tt.date, d.Blackout, tt.blackout)
+		}
+		if got := formatDate(d.ShiftedTo); got != tt.shiftedTo {
+			t.Errorf("%v: EvaluateDate(%v) shifted to %q, want %q", tt.name, tt.date, got, tt.shiftedTo)
+		}
+		if got := formatDate(d.ShiftedFrom); got != tt.shiftedFrom {
+			t.Errorf("%v: EvaluateDate(%v) shifted from %q, want %q", tt.name, tt.date, got, tt.shiftedFrom)
+		}
+	}
+}
+
+// formatDate formats the date, an empty string is returned for the zero time.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,189 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed 5 field cron expression: minute, hour, day of month, month and day of week.
+// Fields support `*`, values, ranges (`1-7`), steps (`*/15`, `1-31/2`) and lists (`1,15`). Months
+// and weekdays can be named (`jan`, `sun`), Sunday is both 0 and 7.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are set when the day of month or day of week field starts with `*`. As with
+	// cron a date matches if either day field matches, unless one of them is unrestricted.
+	domStar, dowStar bool
+}
+
+// cronField describes the range and names of a field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseCron parses a 5 field cron expression.
+func ParseCron(expr string) (Cron, error) {
+	var c Cron
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return c, fmt.Errorf("cron expression %q: expected %v fields, got %v", expr, len(cronFields), len(fields))
+	}
+
+	bits := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, f := range cronFields {
+		b, err := f.parse(fields[i])
+		if err != nil {
+			return c, fmt.Errorf("cron expression %q: %v", expr, err)
+		}
+		*bits[i] = b
+	}
+
+	// Sunday is 0 and 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return c, nil
+}
+
+// MatchesDay returns true if the day of month, month and day of week fields match the date of `t`.
+func (c Cron) MatchesDay(t time.Time) bool {
+	if c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Matches returns true if every field matches `t`, truncated to the minute.
+func (c Cron) Matches(t time.Time) bool {
+	return c.MatchesDay(t) && c.hour&(1<<uint(t.Hour())) != 0 && c.minute&(1<<uint(t.Minute())) != 0
+}
+
+// Next returns the first time after `t`, truncated to the minute, matching every field. It is in the location of
+// `t`. The zero time is returned if no time matches within 5 years, e.g. for the 31st of February.
+func (c Cron) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !c.MatchesDay(next) {
+			next = startOfNextDay(next)
+			continue
+		}
+
+		if c.hour&(1<<uint(next.Hour())) == 0 {
+			// Adding the minutes keeps moving forward where DST skips or repeats an hour, `time.Date` may not.
+			next = next.Add(time.Duration(60-next.Minute()) * time.Minute)
+			continue
+		}
+
+		if c.minute&(1<<uint(next.Minute())) == 0 {
+			next = next.Add(time.Minute)
+			continue
+		}
+
+		return next
+	}
+
+	return time.Time{}
+}
+
+// startOfNextDay returns the first time of the day after `t`. Where DST skips midnight `time.Date` returns
+// a time of the day of `t` instead, the day then starts an hour later.
+func startOfNextDay(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	if day.Day() == t.Day() {
+		day = day.Add(time.Hour)
+	}
+
+	return day
+}
+
+// parse returns the set of values of the field as a bitset.
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %v field %q", f.name, part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// `5/15` means from 5 to the end of the range.
+				hi = f.max
+			}
+
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %v field %q", f.name, part)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// value parses a number or a name of the field.
+func (f cronField) value(s string) (int, error) {
+	lower := strings.ToLower(s)
+	for i, name := range f.names {
+		if name != "" && lower == name {
+			return i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %v field %q", f.name, s)
+	}
+
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%v %v is out of range %v-%v", f.name, n, f.min, f.max)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronMatchesDay(t *testing.T) {
+	tests := []struct {
+		expr string
+		date string
+		want bool
+	}{
+		{"0 3 * * 0", "2026-11-01", true},
+		{"0 3 * * 0", "2026-11-09", false},
+		{"0 3 * * 7", "2026-11-08", true},
+		{"0 3 * * 1-5", "2026-11-09", true},
+		{"0 3 * * mon-fri", "2026-11-08", false},
+		{"0 3 1 * *", "2026-11-01", true},
+		{"0 3 1 * *", "2026-11-02", false},
+		{"0 3 */2 * *", "2026-11-03", true},
+		{"0 3 */2 * *", "2026-11-04", false},
+		{"0 3 1,15 jan-mar *", "2026-03-15", true},
+		{"0 3 1,15 jan-mar *", "2026-11-15", false},
+		// Both day fields are restricted, a date matching either of them matches.
+		{"0 3 1-7 * 0", "2026-11-03", true},
+		{"0 3 1-7 * 0", "2026-11-15", true},
+		{"0 3 1-7 * 0", "2026-11-10", false},
+		// A day field starting with `*` is unrestricted, both fields must match.
+		{"0 3 */1 * 0", "2026-11-03", false},
+		{"0 3 1-7 * */1", "2026-11-10", false},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) error = %v", tt.expr, err)
+			continue
+		}
+
+		if got := c.MatchesDay(date(t, tt.date)); got != tt.want {
+			t.Errorf("ParseCron(%q).MatchesDay(%v) = %v, want %v", tt.expr, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"0 3 * *",
+		"0 3 * * * *",
+		"60 3 * * *",
+		"0 24 * * *",
+		"0 3 0 * *",
+		"0 3 5-1 * *",
+		"0 3 * * */0",
+		"0 3 * foo *",
+		"0 3 * * 8",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) error = nil, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"later the same day", "0 3 * * 0", time.Date(2026, 11, 1, 2, 59, 0, 0, time.UTC), time.Date(2026, 11, 1, 3, 0, 0, 0, time.UTC)},
+		{"strictly after", "0 3 * * 0", time.Date(2026, 11, 1, 3, 0, 0, 0, time.UTC), time.Date(2026, 11, 8, 3, 0, 0, 0, time.UTC)},
+		{"seconds are truncated", "*/15 * * * *", time.Date(2026, 11, 1, 3, 14, 59, 0, time.UTC), time.Date(2026, 11, 1, 3, 15, 0, 0, time.UTC)},
+		{"next month", "30 4 1 * *", time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC), time.Date(2026, 12, 1, 4, 30, 0, 0, time.UTC)},
+		{"in the location of the time", "0 3 * * 0", time.Date(2026, 11, 1, 0, 0, 0, 0, chicago), time.Date(2026, 11, 1, 3, 0, 0, 0, chicago)},
+		// 02:30 does not exist on the day DST starts, the next 02:30 is the day after.
+		{"skipped by DST", "30 2 * * *", time.Date(2026, 3, 8, 0, 0, 0, 0, chicago), time.Date(2026, 3, 9, 2, 30, 0, 0, chicago)},
+		// DST starts at midnight in Chile, the 6th of September 2026 starts at 01:00.
+		{"midnight skipped by DST", "0 12 * * 0", time.Date(2026, 9, 5, 12, 0, 0, 0, santiago), time.Date(2026, 9, 6, 12, 0, 0, 0, santiago)},
+		{"hour repeated by DST", "0 3 * * 0", time.Date(2026, 11, 1, 1, 30, 0, 0, chicago), time.Date(2026, 11, 1, 3, 0, 0, 0, chicago)},
+		{"never", "0 0 31 2 *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("%v: ParseCron(%q) error = %v", tt.name, tt.expr, err)
+		}
+
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%v: Next(%v) = %v, want %v", tt.name, tt.from, got, tt.want)
+		}
+	}
+}
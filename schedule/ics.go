@@ -0,0 +1,272 @@
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseICS returns the events of an iCalendar file as blackouts. Each `VEVENT` blacks out the dates from its
+// `DTSTART` to its `DTEND`, or `DURATION`, and is repeated by its `RRULE` if it has one, except on the dates of
+// its `EXDATE`s. See `ParseRRule` for the supported rules, an event with any other rule fails the whole import.
+// Times and time zones are ignored, an event blacks out every date it touches.
+func parseICS(r io.Reader) ([]period, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []period
+	var event map[string]string
+
+	for _, line := range lines {
+		switch strings.ToUpper(line) {
+		case "BEGIN:VEVENT":
+			event = make(map[string]string)
+			continue
+		case "END:VEVENT":
+			if event == nil {
+				continue
+			}
+			p, err := icsEvent(event)
+			if err != nil {
+				return nil, err
+			}
+			periods = append(periods, p)
+			event = nil
+			continue
+		}
+
+		if event == nil {
+			continue
+		}
+
+		// NAME;PARAM=VALUE:VALUE
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		name := strings.ToUpper(line[:i])
+		if j := strings.Index(name, ";"); j >= 0 {
+			name = name[:j]
+		}
+		// EXDATE can be repeated, its values are lists of dates.
+		if name == "EXDATE" && event[name] != "" {
+			event[name] += "," + line[i+1:]
+			continue
+		}
+		event[name] = line[i+1:]
+	}
+
+	return periods, nil
+}
+
+// icsEvent converts the properties of a `VEVENT` into a period.
+func icsEvent(event map[string]string) (period, error) {
+	p := period{name: unescapeText(event["SUMMARY"])}
+	if p.name == "" {
+		p.name = "calendar event"
+	}
+
+	start, _, err := icsDate(event["DTSTART"])
+	if err != nil {
+		return p, fmt.Errorf("event %q: invalid DTSTART: %v", p.name, err)
+	}
+
+	// DTEND is excluded, the end of an all-day event is the next day.
+	end := start
+	switch {
+	case event["DTEND"] != "":
+		var hasTime bool
+		end, hasTime, err = icsDate(event["DTEND"])
+		if err != nil {
+			return p, fmt.Errorf("event %q: invalid DTEND: %v", p.name, err)
+		}
+		if !hasTime && end.After(start) {
+			end = end.AddDate(0, 0, -1)
+		}
+	case event["DURATION"] != "":
+		days, err := icsDurationDays(event["DURATION"])
+		if err != nil {
+			return p, fmt.Errorf("event %q: invalid DURATION: %v", p.name, err)
+		}
+		if days > 0 {
+			end = start.AddDate(0, 0, days-1)
+		}
+	}
+
+	if end.Before(start) {
+		end = start
+	}
+
+	if rule := event["RRULE"]; rule != "" {
+		rr, err := ParseRRule("DTSTART:" + start.Format("20060102") + " " + rule)
+		if err != nil {
+			return p, fmt.Errorf("event %q: %v", p.name, err)
+		}
+		p.rrule, p.days = &rr, daysBetween(start, end)+1
+
+		if exdates := event["EXDATE"]; exdates != "" {
+			for _, value := range strings.Split(exdates, ",") {
+				d, _, err := icsDate(value)
+				if err != nil {
+					return p, fmt.Errorf("event %q: invalid EXDATE: %v", p.name, err)
+				}
+				p.except = append(p.except, d)
+			}
+		}
+		return p, nil
+	}
+
+	p.from, p.to = start, end
+	return p, nil
+}
+
+// icsDate parses a DATE or DATE-TIME value, returning its date and whether it has a non-midnight time.
+func icsDate(value string) (time.Time, bool, error) {
+	d, err := parseRRuleDate(value)
+	if err != nil {
+		return d, false, err
+	}
+
+	hasTime := false
+	if i := strings.Index(value, "T"); i >= 0 {
+		clock := strings.TrimSuffix(value[i+1:], "Z")
+		hasTime = strings.Trim(clock, "0") != ""
+	}
+
+	return d, hasTime, nil
+}
+
+// icsDurationDays returns the number of dates touched by a duration such as `P1D`, `P2W` or `PT4H`.
+func icsDurationDays(value string) (int, error) {
+	v := strings.TrimPrefix(strings.ToUpper(value), "+")
+	if !strings.HasPrefix(v, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	v = v[1:]
+
+	days := 0
+	if i := strings.Index(v, "W"); i >= 0 {
+		n, err := strconv.Atoi(v[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return n * 7, nil
+	}
+
+	if i := strings.Index(v, "D"); i >= 0 {
+		n, err := strconv.Atoi(v[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		days, v = n, v[i+1:]
+	}
+
+	// A duration with a time part touches one more date.
+	if strings.HasPrefix(v, "T") || days == 0 {
+		days++
+	}
+
+	return days, nil
+}
+
+// WriteICS writes the decisions as an iCalendar file with an all-day event per decision, e.g. to publish the
+// schedule of a cluster. `name` is the name of the cluster used in the summary of the events.
+func WriteICS(w io.Writer, name string, decisions []Decision) error {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//gofailover//schedule//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:" + escapeText(name+" failover schedule"),
+	}
+
+	for _, d := range decisions {
+		summary := fmt.Sprintf("%v: %v", name, d.Action)
+		if d.Blackout != "" {
+			summary += " (blacked out)"
+		}
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%v-%v@gofailover", d.Date.Format("20060102"), icsUID(name)),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+d.Date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+d.Date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+escapeText(summary),
+			"DESCRIPTION:"+escapeText(d.String()),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, fold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fold folds a content line longer than 75 octets, without splitting UTF-8 sequences.
+func fold(line string) string {
+	var b strings.Builder
+
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+
+	return b.String()
+}
+
+// escapeText escapes an iCalendar TEXT value.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsUID returns the name usable in a UID.
+func icsUID(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// unfold returns the lines of an iCalendar file, joining the lines folded with a leading space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// unescapeText unescapes an iCalendar TEXT value.
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
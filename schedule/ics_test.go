@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+)
+
+const holidays = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Holidays//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:christmas@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20261225\r\n" +
+	"DTEND;VALUE=DATE:20261226\r\n" +
+	"RRULE:FREQ=YEARLY;COUNT=5\r\n" +
+	"SUMMARY:Christmas Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:move@example.com\r\n" +
+	"DTSTART:20261120T220000Z\r\n" +
+	"DURATION:P1DT4H\r\n" +
+	"SUMMARY:Datacenter move\\, phase 1\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	periods, err := parseICS(strings.NewReader(holidays))
+	if err != nil {
+		t.Fatalf("parseICS() error = %v", err)
+	}
+
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2026-12-25", "Christmas Day"},
+		{"2030-12-25", "Christmas Day"},
+		{"2026-12-26", ""},
+		// The event repeats 5 times only.
+		{"2031-12-25", ""},
+		{"2026-11-20", "Datacenter move, phase 1"},
+		{"2026-11-21", "Datacenter move, phase 1"},
+		{"2026-11-22", ""},
+	}
+
+	for _, tt := range tests {
+		var got string
+		for _, p := range periods {
+			if p.contains(date(t, tt.date)) {
+				got = p.name
+			}
+		}
+
+		if got != tt.want {
+			t.Errorf("blackout of %v = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestParseICSExdate(t *testing.T) {
+	const calendar = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20261106\r\n" +
+		"DTEND;VALUE=DATE:20261108\r\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=FR\r\n" +
+		"EXDATE;VALUE=DATE:20261113,20261127\r\n" +
+		"EXDATE;TZID=America/Chicago:20261204T000000\r\n" +
+		"SUMMARY:Weekend patching\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	periods, err := parseICS(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("parseICS() error = %v", err)
+	}
+
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2026-11-06", true},
+		{"2026-11-07", true},
+		// Both dates of the excluded occurrences are free.
+		{"2026-11-13", false},
+		{"2026-11-14", false},
+		{"2026-11-20", true},
+		{"2026-11-27", false},
+		{"2026-12-04", false},
+		{"2026-12-05", false},
+		{"2026-12-11", true},
+	}
+
+	for _, tt := range tests {
+		if got := periods[0].contains(date(t, tt.date)); got != tt.want {
+			t.Errorf("blackout of %v = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestParseICSErrors(t *testing.T) {
+	event := func(props string) string {
+		return "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Holiday\r\n" + props + "END:VEVENT\r\nEND:VCALENDAR\r\n"
+	}
+
+	tests := []struct {
+		name     string
+		calendar string
+	}{
+		{"missing DTSTART", event("")},
+		{"invalid DTEND", event("DTSTART;VALUE=DATE:20261225\r\nDTEND;VALUE=DATE:2026\r\n")},
+		{"invalid DURATION", event("DTSTART;VALUE=DATE:20261225\r\nDURATION:1D\r\n")},
+		{"unsupported RRULE part", event("DTSTART;VALUE=DATE:20261225\r\nRRULE:FREQ=MONTHLY;BYDAY=MO;BYSETPOS=-1\r\n")},
+		{"unsupported FREQ", event("DTSTART;VALUE=DATE:20261225\r\nRRULE:FREQ=HOURLY\r\n")},
+		{"invalid EXDATE", event("DTSTART;VALUE=DATE:20261225\r\nRRULE:FREQ=YEARLY\r\nEXDATE:2027\r\n")},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseICS(strings.NewReader(tt.calendar)); err == nil {
+			t.Errorf("%v: parseICS() = nil, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule is a parsed subset of an RFC 5545 recurrence rule matching whole days. `FREQ` (`DAILY`, `WEEKLY`,
+// `MONTHLY` or `YEARLY`), `INTERVAL`, `UNTIL`, `COUNT`, `WKST`, `BYMONTH`, `BYMONTHDAY` and `BYDAY`, including
+// ordinals like `2SA` or `-1SU`, are supported. The other parts are rejected.
+type RRule struct {
+	Freq     string
+	Interval int
+	// Start is the date of `DTSTART`, it is required by `INTERVAL`, `COUNT` and by the rules inheriting the day
+	// of the start date.
+	Start time.Time
+	Until time.Time
+	// Count is the number of occurrences of the rule, counted from the start date. The rule is not bounded if it is 0.
+	Count int
+	// WeekStart is the first day of the week used by `WEEKLY` intervals, Monday by default.
+	WeekStart  time.Weekday
+	ByMonth    []time.Month
+	ByMonthDay []int
+	ByDay      []WeekdayNum
+
+	// last is the date of the last occurrence when the rule is bounded by `Count`.
+	last time.Time
+}
+
+// maxCountYears bounds the search for the last occurrence of a rule with `COUNT`.
+const maxCountYears = 100
+
+// WeekdayNum is a `BYDAY` entry: a weekday and the occurrence of that weekday in the month or year,
+// 0 for every occurrence and negative counting from the end.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// rruleWeekdays are the weekday names of RFC 5545.
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule parses a recurrence rule such as `FREQ=MONTHLY;BYDAY=2SA`. It may be prefixed by `RRULE:` and
+// preceded by a `DTSTART:20260104` line, the lines can be separated by new lines or spaces.
+func ParseRRule(s string) (RRule, error) {
+	rr := RRule{Interval: 1, WeekStart: time.Monday}
+
+	var rule string
+	for _, line := range strings.Fields(s) {
+		switch upper := strings.ToUpper(line); {
+		case strings.HasPrefix(upper, "DTSTART"):
+			start, err := parseRRuleDate(line)
+			if err != nil {
+				return rr, fmt.Errorf("rrule %q: invalid DTSTART: %v", s, err)
+			}
+			rr.Start = start
+		case strings.HasPrefix(upper, "RRULE:"):
+			rule = line[len("RRULE:"):]
+		default:
+			rule = line
+		}
+	}
+
+	if rule == "" {
+		return rr, fmt.Errorf("rrule %q: missing rule", s)
+	}
+
+	if err := rr.parseParts(rule); err != nil {
+		return rr, fmt.Errorf("rrule %q: %v", s, err)
+	}
+
+	if err := rr.validate(); err != nil {
+		return rr, fmt.Errorf("rrule %q: %v", s, err)
+	}
+
+	if rr.Count > 0 {
+		rr.last = rr.lastOccurrence()
+	}
+
+	return rr, nil
+}
+
+func (rr *RRule) parseParts(rule string) error {
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid part %q", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch key {
+		case "FREQ":
+			rr.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rr.Interval = n
+		case "UNTIL":
+			until, err := parseRRuleDate(value)
+			if err != nil {
+				return fmt.Errorf("invalid UNTIL: %v", err)
+			}
+			rr.Until = until
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid COUNT %q", value)
+			}
+			rr.Count = n
+		case "WKST":
+			d, ok := rruleWeekdays[value]
+			if !ok {
+				return fmt.Errorf("invalid WKST %q", value)
+			}
+			rr.WeekStart = d
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				m, err := ParseMonth(v)
+				if err != nil {
+					return fmt.Errorf("invalid BYMONTH: %v", err)
+				}
+				rr.ByMonth = append(rr.ByMonth, m)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return fmt.Errorf("invalid BYMONTHDAY %q", v)
+				}
+				rr.ByMonthDay = append(rr.ByMonthDay, n)
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				if len(v) < 2 {
+					return fmt.Errorf("invalid BYDAY %q", v)
+				}
+
+				d, ok := rruleWeekdays[v[len(v)-2:]]
+				if !ok {
+					return fmt.Errorf("invalid BYDAY %q", v)
+				}
+
+				var n int
+				if num := v[:len(v)-2]; num != "" {
+					var err error
+					if n, err = strconv.Atoi(num); err != nil || n == 0 || n < -53 || n > 53 {
+						return fmt.Errorf("invalid BYDAY %q", v)
+					}
+				}
+				rr.ByDay = append(rr.ByDay, WeekdayNum{Weekday: d, N: n})
+			}
+		default:
+			return fmt.Errorf("%v is not supported", key)
+		}
+	}
+
+	return nil
+}
+
+func (rr RRule) validate() error {
+	switch rr.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return fmt.Errorf("FREQ is required")
+	default:
+		return fmt.Errorf("FREQ=%v is not supported", rr.Freq)
+	}
+
+	if rr.Interval > 1 && rr.Start.IsZero() {
+		return fmt.Errorf("INTERVAL requires DTSTART")
+	}
+
+	if rr.Count > 0 && rr.Start.IsZero() {
+		return fmt.Errorf("COUNT requires DTSTART")
+	}
+
+	if rr.Count > 0 && !rr.Until.IsZero() {
+		return fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+
+	for _, d := range rr.ByDay {
+		if d.N != 0 && rr.Freq != "MONTHLY" && rr.Freq != "YEARLY" {
+			return fmt.Errorf("BYDAY ordinals require FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+
+	if rr.Start.IsZero() && rr.inheritsStart() {
+		return fmt.Errorf("FREQ=%v without BYDAY or BYMONTHDAY requires DTSTART", rr.Freq)
+	}
+
+	return nil
+}
+
+// inheritsStart returns true if the days of the rule are those of the start date.
+func (rr RRule) inheritsStart() bool {
+	if len(rr.ByDay) > 0 || len(rr.ByMonthDay) > 0 {
+		return false
+	}
+
+	return rr.Freq != "DAILY"
+}
+
+// Matches returns true if the rule has an occurrence on the date of `t`.
+func (rr RRule) Matches(t time.Time) bool {
+	day := civil(t)
+
+	if !rr.Start.IsZero() && day.Before(rr.Start) {
+		return false
+	}
+	if !rr.Until.IsZero() && day.After(rr.Until) {
+		return false
+	}
+	if !rr.last.IsZero() && day.After(rr.last) {
+		return false
+	}
+
+	if rr.Interval > 1 && rr.periods(day)%rr.Interval != 0 {
+		return false
+	}
+
+	if len(rr.ByMonth) > 0 && !containsMonth(rr.ByMonth, day.Month()) {
+		return false
+	}
+
+	if rr.inheritsStart() {
+		switch rr.Freq {
+		case "WEEKLY":
+			return day.Weekday() == rr.Start.Weekday()
+		case "MONTHLY":
+			return day.Day() == rr.Start.Day()
+		}
+		// YEARLY, on the day of the start date in the months of BYMONTH or in the month of the start date.
+		return day.Day() == rr.Start.Day() && (len(rr.ByMonth) > 0 || day.Month() == rr.Start.Month())
+	}
+
+	if len(rr.ByMonthDay) > 0 && !rr.matchesMonthDay(day) {
+		return false
+	}
+
+	if len(rr.ByDay) > 0 && !rr.matchesDay(day) {
+		return false
+	}
+
+	return true
+}
+
+// lastOccurrence returns the date of the `Count`th occurrence of the rule from the start date. The date
+// `maxCountYears` after the start date is returned if the rule has fewer occurrences until then.
+func (rr RRule) lastOccurrence() time.Time {
+	limit := rr.Start.AddDate(maxCountYears, 0, 0)
+
+	n := 0
+	for day := rr.Start; day.Before(limit); day = day.AddDate(0, 0, 1) {
+		if rr.Matches(day) {
+			if n++; n == rr.Count {
+				return day
+			}
+		}
+	}
+
+	return limit
+}
+
+// periods returns the number of periods of the frequency between the start date and the day.
+func (rr RRule) periods(day time.Time) int {
+	switch rr.Freq {
+	case "DAILY":
+		return daysBetween(rr.Start, day)
+	case "WEEKLY":
+		return daysBetween(rr.weekOf(rr.Start), rr.weekOf(day)) / 7
+	case "MONTHLY":
+		return (day.Year()-rr.Start.Year())*12 + int(day.Month()) - int(rr.Start.Month())
+	}
+
+	return day.Year() - rr.Start.Year()
+}
+
+// weekOf returns the first day of the week of the day.
+func (rr RRule) weekOf(day time.Time) time.Time {
+	offset := (int(day.Weekday()) - int(rr.WeekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func (rr RRule) matchesMonthDay(day time.Time) bool {
+	last := DaysIn(day.Year(), day.Month())
+	for _, n := range rr.ByMonthDay {
+		if n == day.Day() || n < 0 && last+n+1 == day.Day() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesDay matches the BYDAY entries. Ordinals count in the month for `MONTHLY` rules and `YEARLY`
+// rules with `BYMONTH`, in the year otherwise.
+func (rr RRule) matchesDay(day time.Time) bool {
+	inYear := rr.Freq == "YEARLY" && len(rr.ByMonth) == 0
+
+	for _, d := range rr.ByDay {
+		if d.Weekday != day.Weekday() {
+			continue
+		}
+
+		if d.N == 0 {
+			return true
+		}
+
+		var n, total int
+		if inYear {
+			n = (day.YearDay()-1)/7 + 1
+			total = n + (daysInYear(day.Year())-day.YearDay())/7
+		} else {
+			n = Ordinal(day)
+			total = n + (DaysIn(day.Year(), day.Month())-day.Day())/7
+		}
+
+		if d.N == n || d.N < 0 && total+d.N+1 == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRRuleDate parses the date of a `DTSTART` line or an `UNTIL` value, e.g. `DTSTART;VALUE=DATE:20260104`
+// or `20261231T235959Z`. Only the date is kept.
+func parseRRuleDate(s string) (time.Time, error) {
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		s = s[i+1:]
+	}
+
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+
+	return time.Parse("20060102", s[:8])
+}
+
+// civil returns the date of `t`, in its own location, at midnight UTC.
+func civil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, month := range months {
+		if month == m {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package schedule
+
+import "testing"
+
+func TestRRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule string
+		date string
+		want bool
+	}{
+		{"FREQ=MONTHLY;BYDAY=2SA", "2026-11-14", true},
+		{"RRULE:FREQ=MONTHLY;BYDAY=2SA", "2026-11-07", false},
+		{"FREQ=MONTHLY;BYDAY=-1SU", "2026-11-29", true},
+		{"FREQ=MONTHLY;BYDAY=-1SU", "2026-11-22", false},
+		{"FREQ=YEARLY;BYMONTH=1,4,7,10;BYDAY=1SU", "2026-01-04", true},
+		{"FREQ=YEARLY;BYMONTH=1,4,7,10;BYDAY=1SU", "2026-04-05", true},
+		{"FREQ=YEARLY;BYMONTH=1,4,7,10;BYDAY=1SU", "2026-02-01", false},
+		// Without BYMONTH the ordinal counts in the year.
+		{"FREQ=YEARLY;BYDAY=1MO", "2026-01-05", true},
+		{"FREQ=YEARLY;BYDAY=1MO", "2026-02-02", false},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-02-28", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-02-27", false},
+		{"FREQ=WEEKLY;BYDAY=SA,SU", "2026-11-08", true},
+		{"DTSTART:20261101 FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", "2026-11-01", true},
+		{"DTSTART:20261101 FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", "2026-11-08", false},
+		{"DTSTART:20261101 FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", "2026-11-15", true},
+		{"DTSTART:20261101 FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", "2026-10-18", false},
+		// The days of the start date are inherited.
+		{"DTSTART;VALUE=DATE:20261225\nRRULE:FREQ=YEARLY", "2027-12-25", true},
+		{"DTSTART;VALUE=DATE:20261225\nRRULE:FREQ=YEARLY", "2026-12-24", false},
+		{"DTSTART:20261101 FREQ=MONTHLY", "2026-12-01", true},
+		{"FREQ=DAILY;UNTIL=20261231T235959Z", "2026-12-31", true},
+		{"FREQ=DAILY;UNTIL=20261231T235959Z", "2027-01-01", false},
+		// The occurrences are counted from the start date.
+		{"DTSTART:20261225 FREQ=YEARLY;COUNT=5", "2026-12-25", true},
+		{"DTSTART:20261225 FREQ=YEARLY;COUNT=5", "2030-12-25", true},
+		{"DTSTART:20261225 FREQ=YEARLY;COUNT=5", "2031-12-25", false},
+		{"DTSTART:20261101 FREQ=WEEKLY;BYDAY=SU;COUNT=2", "2026-11-08", true},
+		{"DTSTART:20261101 FREQ=WEEKLY;BYDAY=SU;COUNT=2", "2026-11-15", false},
+		{"DTSTART:20261101 FREQ=MONTHLY;INTERVAL=2;BYDAY=1SU;COUNT=3", "2027-03-07", true},
+		{"DTSTART:20261101 FREQ=MONTHLY;INTERVAL=2;BYDAY=1SU;COUNT=3", "2027-05-02", false},
+	}
+
+	for _, tt := range tests {
+		rr, err := ParseRRule(tt.rule)
+		if err != nil {
+			t.Errorf("ParseRRule(%q) error = %v", tt.rule, err)
+			continue
+		}
+
+		if got := rr.Matches(date(t, tt.date)); got != tt.want {
+			t.Errorf("ParseRRule(%q).Matches(%v) = %v, want %v", tt.rule, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"DTSTART:20261101",
+		"BYDAY=2SA",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;BYDAY=2SA",
+		"FREQ=MONTHLY;BYDAY=2XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY",
+		"FREQ=DAILY;INTERVAL=2",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"DTSTART:2026 FREQ=DAILY",
+		"FREQ=YEARLY;COUNT=5",
+		"DTSTART:20261225 FREQ=YEARLY;COUNT=0",
+		"DTSTART:20261225 FREQ=YEARLY;COUNT=5;UNTIL=20301231",
+	} {
+		if _, err := ParseRRule(rule); err == nil {
+			t.Errorf("ParseRRule(%q) error = nil, want an error", rule)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package schedule decides which action, if any, a scheduled run performs on a given date. A schedule is an
+// ordered list of rules, the first rule matching the date wins. A rule matches dates by weekday, ordinal
+// weekday of the month ("1st", "2nd", "last") and month, by a cron expression or by an RFC 5545 RRULE.
+// Blackouts, such as production freezes or public holidays, skip or shift the actions falling in them.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Action is what a scheduled run does when its rule matches.
+type Action string
+
+// Actions of a rule.
+const (
+	// ActionFailover moves the primary role away from the expected primary node, if it is running there.
+	ActionFailover Action = "failover-to-secondary"
+	// ActionFailback moves the primary role back to the expected primary node, if it is not running there.
+	ActionFailback Action = "failback-to-primary"
+	// ActionVerify only checks the health of the cluster.
+	ActionVerify Action = "verify-only"
+)
+
+// Validate returns an error if the action is unknown.
+func (a Action) Validate() error {
+	switch a {
+	case ActionFailover, ActionFailback, ActionVerify:
+		return nil
+	}
+
+	return fmt.Errorf("unknown action %q, expected %v, %v or %v", a, ActionFailover, ActionFailback, ActionVerify)
+}
+
+// Rule maps the dates it matches to an action. Exactly one of `Weekdays`, `Cron` or `RRule` must be set.
+// `Months` restricts the dates matched by any of them.
+//
+// Example config:
+//
+//	schedule:
+//	  rules:
+//	    - name: change board failover
+//	      weekdays: [Saturday]
+//	      ordinals: [2nd]
+//	      action: failover-to-secondary
+//	    - weekdays: [Saturday]
+//	      ordinals: [last]
+//	      action: failback-to-primary
+//	    - cron: "30 4 * * 6"
+//	      action: verify-only
+//	    - rrule: "FREQ=YEARLY;BYMONTH=1,4,7,10;BYDAY=1SU"
+//	      action: failover-to-secondary
+type Rule struct {
+	// Name is used in the reports, a description of the rule by default.
+	Name   string `mapstructure:"name"`
+	Action Action `mapstructure:"action"`
+
+	// Weekdays are the names of the matched weekdays, e.g. `Sunday` or `sun`.
+	Weekdays []string `mapstructure:"weekdays"`
+	// Ordinals restrict `Weekdays` to their occurrences in the month: `1st` to `5th` or `last`. Every
+	// occurrence is matched if empty.
+	Ordinals []string `mapstructure:"ordinals"`
+	// Months are the names or numbers of the matched months. Every month is matched if empty.
+	Months []string `mapstructure:"months"`
+
+	// Cron is a 5 field cron expression. Only its day of month, month and day of week fields are
+	// used to match a date.
+	Cron string `mapstructure:"cron"`
+	// RRule is an RFC 5545 recurrence rule, optionally preceded by a `DTSTART` line, see `ParseRRule`.
+	RRule string `mapstructure:"rrule"`
+}
+
+// Schedule is an ordered list of rules and the blackouts restricting them.
+type Schedule struct {
+	Rules     []Rule    `mapstructure:"rules"`
+	Blackouts Blackouts `mapstructure:"blackouts"`
+}
+
+// Default returns the schedule used when none is configured: fail over on the 1st `weekday` of the month
+// and fail back on every other `weekday`. `weekday` is Sunday if empty.
+func Default(weekday string) Schedule {
+	if weekday == "" {
+		weekday = "Sunday"
+	}
+
+	return Schedule{Rules: []Rule{
+		{Weekdays: []string{weekday}, Ordinals: []string{"1st"}, Action: ActionFailover},
+		{Weekdays: []string{weekday}, Action: ActionFailback},
+	}}
+}
+
+// Validate returns an error if a rule or a blackout is invalid.
+func (s Schedule) Validate() error {
+	_, err := s.Compile()
+	return err
+}
+
+// Matches returns true if the rule matches the date of `t`. An invalid rule matches nothing.
+func (r Rule) Matches(t time.Time) bool {
+	m, err := r.compile()
+	if err != nil {
+		return false
+	}
+
+	return m.matches(t)
+}
+
+// String returns the name of the rule or a description of the dates it matches.
+func (r Rule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	var desc string
+	switch {
+	case r.Cron != "":
+		desc = "cron " + r.Cron
+	case r.RRule != "":
+		desc = "rrule " + strings.Join(strings.Fields(r.RRule), " ")
+	case len(r.Ordinals) > 0:
+		desc = fmt.Sprintf("%v %v of the month", strings.Join(r.Ordinals, "/"), strings.Join(r.Weekdays, "/"))
+	default:
+		desc = "every " + strings.Join(r.Weekdays, "/")
+	}
+
+	if len(r.Months) > 0 {
+		desc += " in " + strings.Join(r.Months, "/")
+	}
+
+	return desc
+}
+
+// matcher is a compiled rule.
+type matcher struct {
+	weekdays map[time.Weekday]bool
+	ordinals []int
+	months   map[time.Month]bool
+	cron     *Cron
+	rrule    *RRule
+}
+
+// compile parses the fields of the rule.
+func (r Rule) compile() (*matcher, error) {
+	if err := r.Action.Validate(); err != nil {
+		return nil, err
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(r.Weekdays) > 0, r.Cron != "", r.RRule != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("exactly one of `weekdays`, `cron` or `rrule` is required, got %v", kinds)
+	}
+
+	if len(r.Ordinals) > 0 && len(r.Weekdays) == 0 {
+		return nil, fmt.Errorf("`ordinals` require `weekdays`")
+	}
+
+	m := &matcher{}
+
+	if len(r.Weekdays) > 0 {
+		m.weekdays = make(map[time.Weekday]bool)
+		for _, name := range r.Weekdays {
+			d, err := ParseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			m.weekdays[d] = true
+		}
+	}
+
+	for _, o := range r.Ordinals {
+		n, err := ParseOrdinal(o)
+		if err != nil {
+			return nil, err
+		}
+		m.ordinals = append(m.ordinals, n)
+	}
+
+	if len(r.Months) > 0 {
+		m.months = make(map[time.Month]bool)
+		for _, name := range r.Months {
+			month, err := ParseMonth(name)
+			if err != nil {
+				return nil, err
+			}
+			m.months[month] = true
+		}
+	}
+
+	if r.Cron != "" {
+		c, err := ParseCron(r.Cron)
+		if err != nil {
+			return nil, err
+		}
+		m.cron = &c
+	}
+
+	if r.RRule != "" {
+		rr, err := ParseRRule(r.RRule)
+		if err != nil {
+			return nil, err
+		}
+		m.rrule = &rr
+	}
+
+	return m, nil
+}
+
+func (m *matcher) matches(t time.Time) bool {
+	if m.months != nil && !m.months[t.Month()] {
+		return false
+	}
+
+	switch {
+	case m.cron != nil:
+		return m.cron.MatchesDay(t)
+	case m.rrule != nil:
+		return m.rrule.Matches(t)
+	}
+
+	if !m.weekdays[t.Weekday()] {
+		return false
+	}
+
+	if len(m.ordinals) == 0 {
+		return true
+	}
+
+	for _, n := range m.ordinals {
+		if n == Ordinal(t) || n == -1 && IsLast(t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// date returns midnight UTC of the date written `2006-01-02`.
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestOrdinal(t *testing.T) {
+	tests := []struct {
+		date    string
+		ordinal int
+		last    bool
+	}{
+		{"2026-11-01", 1, false},
+		{"2026-11-08", 2, false},
+		{"2026-11-29", 5, true},
+		{"2026-10-25", 4, true},
+		{"2026-05-24", 4, false},
+		{"2026-05-31", 5, true},
+		// February 2026 has exactly 4 weeks.
+		{"2026-02-22", 4, true},
+		{"2026-02-28", 4, true},
+		{"2024-02-29", 5, true},
+	}
+
+	for _, tt := range tests {
+		d := date(t, tt.date)
+		if got := Ordinal(d); got != tt.ordinal {
+			t.Errorf("Ordinal(%v) = %v, want %v", tt.date, got, tt.ordinal)
+		}
+		if got := IsLast(d); got != tt.last {
+			t.Errorf("IsLast(%v) = %v, want %v", tt.date, got, tt.last)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		date string
+		want bool
+	}{
+		{"1st Sunday", Rule{Weekdays: []string{"Sunday"}, Ordinals: []string{"1st"}}, "2026-11-01", true},
+		{"not the 1st Sunday", Rule{Weekdays: []string{"Sunday"}, Ordinals: []string{"1st"}}, "2026-11-08", false},
+		{"1st Sunday of March", Rule{Weekdays: []string{"sun"}, Ordinals: []string{"first"}}, "2026-03-01", true},
+		{"last Saturday", Rule{Weekdays: []string{"Saturday"}, Ordinals: []string{"last"}}, "2026-10-31", true},
+		{"not the last Saturday", Rule{Weekdays: []string{"Saturday"}, Ordinals: []string{"last"}}, "2026-10-24", false},
+		{"4th and last Sunday", Rule{Weekdays: []string{"Sunday"}, Ordinals: []string{"4th", "last"}}, "2026-02-22", true},
+		{"every Sunday", Rule{Weekdays: []string{"Sunday"}}, "2026-11-29", true},
+		{"wrong weekday", Rule{Weekdays: []string{"Sunday"}}, "2026-11-09", false},
+		{"in the month", Rule{Weekdays: []string{"Sunday"}, Months: []string{"Jan", "4", "July", "oct"}}, "2026-10-04", true},
+		{"outside of the months", Rule{Weekdays: []string{"Sunday"}, Months: []string{"Jan", "4", "July", "oct"}}, "2026-11-01", false},
+		{"cron", Rule{Cron: "0 3 1-7 * 0"}, "2026-11-03", true},
+		{"cron in the month", Rule{Cron: "0 3 * * 0", Months: []string{"March"}}, "2026-11-01", false},
+		{"rrule", Rule{RRule: "FREQ=MONTHLY;BYDAY=2SA"}, "2026-11-14", true},
+		{"invalid rule", Rule{Weekdays: []string{"Sunday"}, Cron: "0 3 * * 0"}, "2026-11-01", false},
+	}
+
+	for _, tt := range tests {
+		tt.rule.Action = ActionFailover
+		if got := tt.rule.Matches(date(t, tt.date)); got != tt.want {
+			t.Errorf("%v: Matches(%v) = %v, want %v", tt.name, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown action", Rule{Weekdays: []string{"Sunday"}, Action: "reboot"}},
+		{"no dates", Rule{Action: ActionFailover}},
+		{"two kinds", Rule{Weekdays: []string{"Sunday"}, RRule: "FREQ=WEEKLY;BYDAY=SU", Action: ActionFailover}},
+		{"ordinals without weekdays", Rule{Cron: "0 3 * * 0", Ordinals: []string{"1st"}, Action: ActionFailover}},
+		{"unknown weekday", Rule{Weekdays: []string{"Sundy"}, Action: ActionFailover}},
+		{"unknown ordinal", Rule{Weekdays: []string{"Sunday"}, Ordinals: []string{"6th"}, Action: ActionFailover}},
+		{"unknown month", Rule{Weekdays: []string{"Sunday"}, Months: []string{"13"}, Action: ActionFailover}},
+	}
+
+	for _, tt := range tests {
+		if err := (Schedule{Rules: []Rule{tt.rule}}).Validate(); err == nil {
+			t.Errorf("%v: Validate() error = nil, want an error", tt.name)
+		}
+	}
+}